@@ -0,0 +1,147 @@
+package sse
+
+import (
+	"net/http"
+	"sync"
+)
+
+// maxPendingAcks bounds how many unacknowledged event ids are retained per session
+const maxPendingAcks = 4096
+
+// AckTracker correlates acknowledgments POSTed by clients with the events delivered on their
+// connection. AckTracker is an http.Handler for the acknowledgment endpoint, which accepts
+// POST requests with session and id form values.
+type AckTracker struct {
+	mutex    sync.Mutex
+	sessions map[string]*ackSession
+}
+
+// AckStatus reports the delivery and acknowledgment progress of a session
+type AckStatus struct {
+	LastDelivered string
+	LastAcked     string
+	Unacked       int
+}
+
+type ackSession struct {
+	pending  []string
+	overflow int
+	status   AckStatus
+}
+
+// NewAckTracker returns an AckTracker without any sessions
+func NewAckTracker() *AckTracker {
+	return &AckTracker{sessions: make(map[string]*ackSession)}
+}
+
+// Session returns an Option that records events delivered on the upgraded connection under
+// sessionID, the id clients acknowledge events with. The session is tracked from when the
+// connection is upgraded until it terminates.
+func (tracker *AckTracker) Session(sessionID string) Option {
+	return func(o *options) {
+		var session *ackSession
+		o.onUpgrade = append(o.onUpgrade, func(*Connection) {
+			session = tracker.track(sessionID)
+		})
+		o.onClose = append(o.onClose, func(error) {
+			tracker.forget(sessionID, session)
+		})
+		WithDeliveryCallback(func(id string) {
+			if len(id) > 0 {
+				tracker.delivered(sessionID, id)
+			}
+		})(o)
+	}
+}
+
+// track starts tracking a new session under sessionID, replacing the session of an earlier
+// connection with the id
+func (tracker *AckTracker) track(sessionID string) *ackSession {
+	session := &ackSession{}
+	tracker.mutex.Lock()
+	tracker.sessions[sessionID] = session
+	tracker.mutex.Unlock()
+	return session
+}
+
+// forget stops tracking session unless a later connection replaced it
+func (tracker *AckTracker) forget(sessionID string, session *ackSession) {
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+	if tracker.sessions[sessionID] == session {
+		delete(tracker.sessions, sessionID)
+	}
+}
+
+// Status returns the acknowledgment progress of a session and whether the session exists
+func (tracker *AckTracker) Status(sessionID string) (AckStatus, bool) {
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+	if session, ok := tracker.sessions[sessionID]; ok {
+		return session.status, true
+	}
+	return AckStatus{}, false
+}
+
+// Forget stops tracking a session before its connection terminates
+func (tracker *AckTracker) Forget(sessionID string) {
+	tracker.mutex.Lock()
+	delete(tracker.sessions, sessionID)
+	tracker.mutex.Unlock()
+}
+
+// ServeHTTP acknowledges that a session's client has processed every event up to and
+// including the event id in the request's form values
+func (tracker *AckTracker) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodPost {
+		writer.Header().Set("Allow", http.MethodPost)
+		http.Error(writer, "acknowledgments must be POSTed", http.StatusMethodNotAllowed)
+		return
+	}
+	sessionID := request.FormValue("session")
+	id := request.FormValue("id")
+	if len(sessionID) == 0 || len(id) == 0 {
+		http.Error(writer, "session and id are required", http.StatusBadRequest)
+		return
+	}
+	if !tracker.ack(sessionID, id) {
+		http.Error(writer, "unknown session", http.StatusNotFound)
+		return
+	}
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+func (tracker *AckTracker) delivered(sessionID string, id string) {
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+	session, ok := tracker.sessions[sessionID]
+	if !ok {
+		return
+	}
+	if len(session.pending) == maxPendingAcks {
+		session.pending = session.pending[1:]
+		session.overflow++
+	}
+	session.pending = append(session.pending, id)
+	session.status.LastDelivered = id
+	session.status.Unacked = session.overflow + len(session.pending)
+}
+
+func (tracker *AckTracker) ack(sessionID string, id string) bool {
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+	session, ok := tracker.sessions[sessionID]
+	if !ok {
+		return false
+	}
+	for i, pending := range session.pending {
+		if pending == id {
+			session.pending = session.pending[i+1:]
+			session.overflow = 0
+			session.status.LastAcked = id
+			session.status.Unacked = len(session.pending)
+			break
+		}
+	}
+	return true
+}
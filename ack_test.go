@@ -0,0 +1,57 @@
+package sse
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAckTrackerSession(t *testing.T) {
+	tracker := NewAckTracker()
+	option := tracker.Session("session")
+	if _, ok := tracker.Status("session"); ok {
+		t.Fatal("session tracked before upgrade")
+	}
+	connection, err := Upgrade(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil), option, WithQueueSize(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"1", "2", "3"} {
+		connection.Send(Message{ID: id, Data: []byte(id)})
+	}
+	connection.SendAndWait(context.Background(), Message{ID: "4", Data: []byte("4")})
+	ack := httptest.NewRequest(http.MethodPost, "/acks", strings.NewReader(url.Values{"session": {"session"}, "id": {"2"}}.Encode()))
+	ack.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	recorder := httptest.NewRecorder()
+	tracker.ServeHTTP(recorder, ack)
+	if recorder.Code != http.StatusNoContent {
+		t.Errorf("acknowledgment responded %d", recorder.Code)
+	}
+	status, ok := tracker.Status("session")
+	if want := (AckStatus{LastDelivered: "4", LastAcked: "2", Unacked: 2}); !ok || status != want {
+		t.Errorf("Status() = %+v, %v, want %+v", status, ok, want)
+	}
+
+	// a reconnecting client's new session outlives the connection it replaced
+	reconnected, err := Upgrade(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil), tracker.Session("session"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	connection.Close()
+	if _, ok := tracker.Status("session"); !ok {
+		t.Error("session forgotten on closing the connection it replaced")
+	}
+	reconnected.Close()
+	// OnClose callbacks may still be running when Close returns
+	deadline := time.Now().Add(time.Second)
+	for _, ok := tracker.Status("session"); ok; _, ok = tracker.Status("session") {
+		if time.Now().After(deadline) {
+			t.Fatal("session tracked after its connection terminated")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
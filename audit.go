@@ -0,0 +1,75 @@
+package sse
+
+import (
+	"math/rand"
+	"net/http"
+	"time"
+)
+
+// AuditKind identifies the stream activity described by an AuditRecord
+type AuditKind string
+
+const (
+	// AuditOpen is recorded when a request is upgraded to an event stream
+	AuditOpen AuditKind = "open"
+	// AuditClose is recorded when an event stream ends
+	AuditClose AuditKind = "close"
+	// AuditDelivery is recorded when an event has been written to the client
+	AuditDelivery AuditKind = "delivery"
+	// AuditSubscribe is recorded for each topic a Broker subscribes a connection to
+	AuditSubscribe AuditKind = "subscribe"
+	// AuditUnsubscribe is recorded for each topic of a Broker's connection once it ends
+	AuditUnsubscribe AuditKind = "unsubscribe"
+)
+
+// AuditRecord is a structured record of stream activity. Request is the upgraded request
+// so sinks can derive the identity of the client from its headers or context.
+type AuditRecord struct {
+	Kind    AuditKind
+	Time    time.Time
+	Request *http.Request
+	ID      string
+	Event   string
+	Topic   string
+}
+
+// AuditSink receives AuditRecords for connection activity
+type AuditSink interface {
+	Audit(record AuditRecord)
+}
+
+// WithAudit sends records of the connection opening and closing, and of subscribing to and
+// unsubscribing from a Broker's topics, to sink. A fraction of event
+// deliveries are also recorded as set by deliverySampleRate, where 0 records none and 1
+// records every event.
+func WithAudit(sink AuditSink, deliverySampleRate float64) Option {
+	return func(o *options) {
+		o.auditSink = sink
+		o.auditDeliverySampleRate = deliverySampleRate
+	}
+}
+
+func (o *options) audit(kind AuditKind, request *http.Request, message *Message) {
+	if o.auditSink == nil {
+		return
+	}
+	record := AuditRecord{Kind: kind, Time: time.Now(), Request: request}
+	if message != nil {
+		if o.auditDeliverySampleRate <= 0 || rand.Float64() >= o.auditDeliverySampleRate {
+			return
+		}
+		record.ID = message.ID
+		record.Event = message.Event
+	}
+	o.auditSink.Audit(record)
+}
+
+// auditTopics records kind for each of the topics a Broker's connection subscribed to
+func (o *options) auditTopics(kind AuditKind, request *http.Request, topics []string) {
+	if o.auditSink == nil {
+		return
+	}
+	for _, topic := range topics {
+		o.auditSink.Audit(AuditRecord{Kind: kind, Time: time.Now(), Request: request, Topic: topic})
+	}
+}
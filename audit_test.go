@@ -0,0 +1,67 @@
+package sse
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// auditLog is an AuditSink keeping the records it receives
+type auditLog struct {
+	mutex   sync.Mutex
+	records []AuditRecord
+}
+
+func (log *auditLog) Audit(record AuditRecord) {
+	log.mutex.Lock()
+	defer log.mutex.Unlock()
+	log.records = append(log.records, record)
+}
+
+func (log *auditLog) kinds() []string {
+	log.mutex.Lock()
+	defer log.mutex.Unlock()
+	kinds := make([]string, len(log.records))
+	for i, record := range log.records {
+		kinds[i] = string(record.Kind) + " " + record.Topic
+	}
+	return kinds
+}
+
+func TestBrokerAuditsSubscriptions(t *testing.T) {
+	log := &auditLog{}
+	broker := NewBroker(WithAudit(log, 0))
+	server := httptest.NewServer(broker)
+	defer server.Close()
+	response, err := http.Get(server.URL + "?topic=orders&topic=payments")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+	deadline := time.Now().Add(time.Second)
+	for broker.Len() == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := broker.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"open ", "subscribe orders", "subscribe payments", "unsubscribe orders", "unsubscribe payments", "close "}
+	for time.Now().Before(deadline) && len(log.kinds()) < len(want) {
+		time.Sleep(time.Millisecond)
+	}
+	kinds := log.kinds()
+	if len(kinds) != len(want) {
+		t.Fatalf("audited %q, want %q", kinds, want)
+	}
+	for i := range want {
+		if kinds[i] != want[i] {
+			t.Errorf("audited %q, want %q", kinds, want)
+			break
+		}
+	}
+}
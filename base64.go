@@ -0,0 +1,19 @@
+package sse
+
+import "encoding/base64"
+
+// SendBase64 sends binary data base64 encoded as an event's data without an id or event
+// field. Clients decode it with Event.Base64Data or atob in a browser.
+func (connection *Connection) SendBase64(data []byte) error {
+	return connection.BuildMessage().SendBase64(data)
+}
+
+// SendBase64 sends binary data base64 encoded with the specified id and event attributes
+func (messageBuilder *MessageBuilder) SendBase64(data []byte) error {
+	return messageBuilder.SendBytes(base64.StdEncoding.AppendEncode(nil, data))
+}
+
+// Base64Data decodes the data of an event sent with SendBase64
+func (event Event) Base64Data() ([]byte, error) {
+	return base64.StdEncoding.DecodeString(event.Data)
+}
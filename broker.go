@@ -0,0 +1,299 @@
+package sse
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// brokerQueueSize is the queue size of a Broker's connections unless its options set one, so
+// publishing doesn't depend on each connection's writer being ready
+const brokerQueueSize = 64
+
+// TopicParam is the query parameter naming the topics that Broker.ServeHTTP subscribes a
+// request to
+const TopicParam = "topic"
+
+// Broker publishes messages to the connections subscribed to their topic, tracking connections
+// from upgrade until they terminate
+type Broker struct {
+	opts        []Option
+	options     *options
+	mutex       sync.Mutex
+	connections map[*Connection][]string
+	topics      map[string]map[*Connection]struct{}
+	patterns    map[string]map[*Connection]struct{}
+	settings    map[string]func(connection *Connection)
+	closed      bool
+}
+
+// NewBroker returns a Broker upgrading subscribers with opts. Connections queue up to 64
+// messages unless opts include WithQueueSize.
+func NewBroker(opts ...Option) *Broker {
+	opts = append([]Option{WithQueueSize(brokerQueueSize)}, opts...)
+	return &Broker{
+		opts:        opts,
+		options:     newOptions(opts),
+		connections: make(map[*Connection][]string),
+		topics:      make(map[string]map[*Connection]struct{}),
+		patterns:    make(map[string]map[*Connection]struct{}),
+		settings:    make(map[string]func(connection *Connection)),
+	}
+}
+
+// Subscribe upgrades the request and streams the messages published to topics and broadcast by
+// the broker, returning once the connection terminates, so handlers can call it as their last
+// statement. Topics can be wildcard patterns matching families of topics, see MatchTopic, and
+// receive messages published to related topics by the broker's WithTopicHierarchy.
+// Subscribe returns Upgrade's error if the request isn't upgraded, or
+// ErrBrokerClosed, responding 503 Service Unavailable, after Shutdown.
+func (broker *Broker) Subscribe(writer http.ResponseWriter, request *http.Request, topics ...string) error {
+	broker.mutex.Lock()
+	closed := broker.closed
+	broker.mutex.Unlock()
+	if closed {
+		return refuse(writer, http.StatusServiceUnavailable, ErrBrokerClosed, 0)
+	}
+	connection, err := Upgrade(writer, request, broker.opts...)
+	if err != nil {
+		return err
+	}
+	if err := broker.subscribe(connection, topics); err != nil {
+		connection.Close()
+		return err
+	}
+	broker.options.auditTopics(AuditSubscribe, request, topics)
+	connection.OnClose(func(error) {
+		broker.unsubscribe(connection)
+		broker.options.auditTopics(AuditUnsubscribe, request, topics)
+	})
+	<-connection.Done()
+	return nil
+}
+
+// subscribe tracks connection as subscribed to topics, or returns ErrBrokerClosed after
+// Shutdown
+func (broker *Broker) subscribe(connection *Connection, topics []string) error {
+	broker.mutex.Lock()
+	defer broker.mutex.Unlock()
+	if broker.closed {
+		return ErrBrokerClosed
+	}
+	broker.connections[connection] = topics
+	for _, setting := range broker.settings {
+		setting(connection)
+	}
+	for _, topic := range topics {
+		subscriptions := broker.subscriptions(topic)
+		if subscriptions[topic] == nil {
+			subscriptions[topic] = make(map[*Connection]struct{})
+		}
+		subscriptions[topic][connection] = struct{}{}
+	}
+	return nil
+}
+
+// unsubscribe stops tracking a terminated connection
+func (broker *Broker) unsubscribe(connection *Connection) {
+	broker.mutex.Lock()
+	defer broker.mutex.Unlock()
+	for _, topic := range broker.connections[connection] {
+		subscriptions := broker.subscriptions(topic)
+		delete(subscriptions[topic], connection)
+		if len(subscriptions[topic]) == 0 {
+			delete(subscriptions, topic)
+		}
+	}
+	delete(broker.connections, connection)
+}
+
+// subscriptions returns the broker's subscriptions to exact topics, or to patterns for a topic
+// with wildcards
+func (broker *Broker) subscriptions(topic string) map[string]map[*Connection]struct{} {
+	if isTopicPattern(topic, broker.options.separator()) {
+		return broker.patterns
+	}
+	return broker.topics
+}
+
+// subscribers returns the connections that a message published to topic reaches, subscribed
+// to it exactly or by pattern, or to the topics it propagates to in the topic hierarchy
+func (broker *Broker) subscribers(topic string) map[*Connection]struct{} {
+	propagation := broker.options.topicPropagation
+	if len(broker.patterns) == 0 && propagation == 0 {
+		return broker.topics[topic]
+	}
+	separator := broker.options.separator()
+	topics := []string{topic}
+	if propagation&PropagateToAncestors != 0 {
+		topics = append(topics, topicAncestors(topic, separator)...)
+	}
+	descendants := propagation&PropagateToDescendants != 0
+	subscribers := make(map[*Connection]struct{}, len(broker.topics[topic]))
+	reach := func(connections map[*Connection]struct{}) {
+		for connection := range connections {
+			subscribers[connection] = struct{}{}
+		}
+	}
+	for _, reached := range topics {
+		reach(broker.topics[reached])
+	}
+	if descendants {
+		for subscribed, connections := range broker.topics {
+			if strings.HasPrefix(subscribed, topic+separator) {
+				reach(connections)
+			}
+		}
+	}
+	for pattern, connections := range broker.patterns {
+		if matchTopic(pattern, topic, separator, descendants) {
+			reach(connections)
+			continue
+		}
+		for _, ancestor := range topics[1:] {
+			if matchTopic(pattern, ancestor, separator, false) {
+				reach(connections)
+				break
+			}
+		}
+	}
+	return subscribers
+}
+
+// ServeHTTP subscribes the request to the topics named by its TopicParam query parameters,
+// logging requests that couldn't be upgraded with the broker's logger. Clients choose their
+// own topics, so handlers serving topics that need authorizing should call Subscribe instead.
+func (broker *Broker) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	if err := broker.Subscribe(writer, request, request.URL.Query()[TopicParam]...); err != nil {
+		broker.options.logger.Println("sse upgrade error: " + err.Error())
+	}
+}
+
+// Publish sends message to every connection subscribed to topic through the connections'
+// middleware, returning how many it was queued for. Publish never waits for a slow client: a
+// connection with a full queue is handled by its OverflowPolicy, and with OverflowBlock misses
+// the message.
+func (broker *Broker) Publish(topic string, message Message) int {
+	broker.mutex.Lock()
+	subscribers := broker.subscribers(topic)
+	connections := make([]*Connection, 0, len(subscribers))
+	for connection := range subscribers {
+		connections = append(connections, connection)
+	}
+	broker.mutex.Unlock()
+	return publish(connections, message)
+}
+
+// Broadcast queues message for every connection subscribed to the broker, whatever its topics,
+// returning how many it was queued for. Like Publish, Broadcast never waits for a slow client.
+func (broker *Broker) Broadcast(message Message) int {
+	return publish(broker.Connections(), message)
+}
+
+// publish sends message to connections without waiting, returning how many it was queued for
+func publish(connections []*Connection, message Message) int {
+	published := 0
+	for _, connection := range connections {
+		ctx, offered := offerContext()
+		sent := message
+		if connection.sendContext(ctx, &sent) == nil && offered.isQueued() {
+			published++
+		}
+	}
+	return published
+}
+
+// Connections returns the connections currently subscribed to the broker
+func (broker *Broker) Connections() []*Connection {
+	broker.mutex.Lock()
+	defer broker.mutex.Unlock()
+	connections := make([]*Connection, 0, len(broker.connections))
+	for connection := range broker.connections {
+		connections = append(connections, connection)
+	}
+	return connections
+}
+
+// Len returns how many connections are subscribed to the broker
+func (broker *Broker) Len() int {
+	broker.mutex.Lock()
+	defer broker.mutex.Unlock()
+	return len(broker.connections)
+}
+
+// Subscribers returns how many connections are subscribed to topic, exactly or by pattern
+func (broker *Broker) Subscribers(topic string) int {
+	broker.mutex.Lock()
+	defer broker.mutex.Unlock()
+	return len(broker.subscribers(topic))
+}
+
+// Shutdown stops the broker accepting subscribers and closes its connections, delivering the
+// messages already queued for them. With WithStaggeredReconnect each connection is closed with
+// a random retry within the window. Connections still open when ctx is done are terminated
+// without delivering the rest of their queue, and Shutdown returns ctx's error.
+func (broker *Broker) Shutdown(ctx context.Context) error {
+	broker.mutex.Lock()
+	broker.closed = true
+	broker.mutex.Unlock()
+	connections := broker.Connections()
+	closed := make(chan struct{})
+	go func() {
+		var wg sync.WaitGroup
+		for _, connection := range connections {
+			wg.Add(1)
+			go func(connection *Connection) {
+				defer wg.Done()
+				connection.closeAfter(broker.options.staggeredRetry())
+			}(connection)
+		}
+		wg.Wait()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+		return nil
+	case <-ctx.Done():
+		for _, connection := range connections {
+			connection.abort(ctx.Err())
+		}
+		return ctx.Err()
+	}
+}
+
+// SetHeartbeat changes the heartbeat interval of the broker's connections, and of those that
+// subscribe later, as with Connection.SetHeartbeat
+func (broker *Broker) SetHeartbeat(interval time.Duration) {
+	broker.tune("heartbeat", func(connection *Connection) {
+		connection.SetHeartbeat(interval)
+	})
+}
+
+// SetWriteTimeout changes the write deadline of the broker's connections, and of those that
+// subscribe later, as with Connection.SetWriteTimeout
+func (broker *Broker) SetWriteTimeout(timeout time.Duration) {
+	broker.tune("write timeout", func(connection *Connection) {
+		connection.SetWriteTimeout(timeout)
+	})
+}
+
+// SetQueueLimit changes the queue limit of the broker's connections, and of those that
+// subscribe later, as with Connection.SetQueueLimit
+func (broker *Broker) SetQueueLimit(limit int) {
+	broker.tune("queue limit", func(connection *Connection) {
+		connection.SetQueueLimit(limit)
+	})
+}
+
+// tune applies a setting to the broker's connections, replacing the setting of the name that
+// connections subscribing later are given
+func (broker *Broker) tune(name string, setting func(connection *Connection)) {
+	broker.mutex.Lock()
+	defer broker.mutex.Unlock()
+	broker.settings[name] = setting
+	for connection := range broker.connections {
+		setting(connection)
+	}
+}
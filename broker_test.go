@@ -0,0 +1,128 @@
+package sse
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestBrokerPublishesToTopicSubscribers(t *testing.T) {
+	broker := NewBroker()
+	server := httptest.NewServer(broker)
+	defer server.Close()
+	decoders := make(map[string]*Decoder)
+	for _, topic := range []string{"orders", "payments"} {
+		response, err := http.Get(server.URL + "?" + TopicParam + "=" + topic)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer response.Body.Close()
+		decoders[topic] = NewDecoder(response.Body)
+	}
+	deadline := time.Now().Add(time.Second)
+	for broker.Len() < 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if subscribers := broker.Subscribers("orders"); subscribers != 1 {
+		t.Fatalf("Subscribers(orders) = %d, want 1", subscribers)
+	}
+	if published := broker.Publish("orders", Message{Event: "created", Data: []byte("order")}); published != 1 {
+		t.Errorf("Publish(orders) = %d, want 1", published)
+	}
+	if published := broker.Publish("refunds", Message{Data: []byte("refund")}); published != 0 {
+		t.Errorf("Publish(refunds) = %d, want 0", published)
+	}
+	if broadcast := broker.Broadcast(Message{Event: "notice", Data: []byte("all")}); broadcast != 2 {
+		t.Errorf("Broadcast() = %d, want 2", broadcast)
+	}
+	want := map[string][]string{"orders": {"created", "notice"}, "payments": {"notice"}}
+	for topic, events := range want {
+		for _, event := range events {
+			decoded, err := decoders[topic].Decode()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if decoded.Event != event {
+				t.Errorf("%s subscriber received %q, want %q", topic, decoded.Event, event)
+			}
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := broker.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// subscribed returns a broker whose subscribers of each topic are placeholder connections
+func subscribed(t *testing.T, broker *Broker, topics ...string) map[string]*Connection {
+	connections := make(map[string]*Connection, len(topics))
+	for _, topic := range topics {
+		connections[topic] = &Connection{}
+		if err := broker.subscribe(connections[topic], []string{topic}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return connections
+}
+
+func TestBrokerWildcardSubscribers(t *testing.T) {
+	broker := NewBroker()
+	connections := subscribed(t, broker, "orders.created", "orders.*", "orders.#", "*.created", "payments.#")
+	tests := []struct {
+		topic string
+		want  []string
+	}{
+		{"orders.created", []string{"orders.created", "orders.*", "orders.#", "*.created"}},
+		{"orders.eu.created", []string{"orders.#"}},
+		{"orders", []string{"orders.#"}},
+		{"payments.created", []string{"*.created", "payments.#"}},
+		{"refunds", nil},
+	}
+	for _, test := range tests {
+		subscribers := broker.subscribers(test.topic)
+		if len(subscribers) != len(test.want) {
+			t.Errorf("subscribers(%q) = %d connections, want %d", test.topic, len(subscribers), len(test.want))
+		}
+		for _, subscription := range test.want {
+			if _, ok := subscribers[connections[subscription]]; !ok {
+				t.Errorf("subscribers(%q) is missing the subscriber of %q", test.topic, subscription)
+			}
+		}
+	}
+}
+
+func TestBrokerHierarchySubscribers(t *testing.T) {
+	topics := []string{"acme", "acme/eu", "acme/eu/api", "acme/us/api", "acme/*/api", "*/eu", "other"}
+	tests := []struct {
+		name        string
+		propagation TopicPropagation
+		topic       string
+		want        []string
+	}{
+		{"exact", 0, "acme/eu", []string{"acme/eu", "*/eu"}},
+		{"ancestors", PropagateToAncestors, "acme/eu", []string{"acme/eu", "*/eu", "acme"}},
+		{"ancestors by pattern", PropagateToAncestors, "acme/eu/api", []string{"acme/eu/api", "acme/*/api", "acme/eu", "acme", "*/eu"}},
+		{"descendants", PropagateToDescendants, "acme/eu", []string{"acme/eu", "*/eu", "acme/eu/api", "acme/*/api"}},
+		{"descendants of root", PropagateToDescendants, "acme", []string{"acme", "acme/eu", "acme/eu/api", "acme/us/api", "acme/*/api", "*/eu"}},
+		{"both", PropagateToAncestors | PropagateToDescendants, "acme/eu", []string{"acme", "acme/eu", "*/eu", "acme/eu/api", "acme/*/api"}},
+		{"unrelated", PropagateToAncestors | PropagateToDescendants, "other", []string{"other", "*/eu"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			broker := NewBroker(WithTopicHierarchy("/", test.propagation))
+			connections := subscribed(t, broker, topics...)
+			subscribers := broker.subscribers(test.topic)
+			if len(subscribers) != len(test.want) {
+				t.Errorf("subscribers(%q) = %d connections, want %d", test.topic, len(subscribers), len(test.want))
+			}
+			for _, subscription := range test.want {
+				if _, ok := subscribers[connections[subscription]]; !ok {
+					t.Errorf("subscribers(%q) is missing the subscriber of %q", test.topic, subscription)
+				}
+			}
+		})
+	}
+}
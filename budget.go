@@ -0,0 +1,51 @@
+package sse
+
+import "sync/atomic"
+
+// LimitReachedEvent is the event sent before a connection is closed for exhausting its Budget.
+// The event's data names the exhausted limit, either "bytes" or "events".
+const LimitReachedEvent = "limit-reached"
+
+// Budget reports a connection's delivery limits and how much of them has been used. A limit
+// of 0 is unlimited.
+type Budget struct {
+	MaxBytes  int64
+	MaxEvents int64
+	Bytes     int64
+	Events    int64
+}
+
+// WithBudget caps the total bytes of encoded events and the number of events delivered on
+// the connection. When a send would exceed either limit, a LimitReachedEvent is sent and the
+// connection is closed. A limit of 0 is unlimited.
+func WithBudget(maxBytes int64, maxEvents int64) Option {
+	return func(o *options) {
+		o.maxBytes = maxBytes
+		o.maxEvents = maxEvents
+	}
+}
+
+// Budget returns the connection's delivery limits and usage
+func (connection *Connection) Budget() Budget {
+	return Budget{
+		MaxBytes:  connection.options.maxBytes,
+		MaxEvents: connection.options.maxEvents,
+		Bytes:     atomic.LoadInt64(&connection.bytesSent),
+		Events:    atomic.LoadInt64(&connection.eventsSent),
+	}
+}
+
+// charge counts an event of size bytes against the connection's budget, returning the name
+// of the exhausted limit if delivering it would exceed the budget
+func (connection *Connection) charge(size int64) string {
+	options := connection.options
+	if options.maxEvents > 0 && atomic.LoadInt64(&connection.eventsSent)+1 > options.maxEvents {
+		return "events"
+	}
+	if options.maxBytes > 0 && atomic.LoadInt64(&connection.bytesSent)+size > options.maxBytes {
+		return "bytes"
+	}
+	atomic.AddInt64(&connection.eventsSent, 1)
+	atomic.AddInt64(&connection.bytesSent, size)
+	return ""
+}
@@ -0,0 +1,28 @@
+// Command sse-debug proxies requests to an SSE server and prints the decoded frames of the
+// streams passing through it.
+//
+//	sse-debug -listen :8081 -target http://localhost:8080
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+
+	"github.com/eighty4/sse/ssedebug"
+)
+
+func main() {
+	listen := flag.String("listen", ":8081", "address to accept client connections on")
+	target := flag.String("target", "http://localhost:8080", "url of the SSE server")
+	flag.Parse()
+
+	targetURL, err := url.Parse(*target)
+	if err != nil {
+		log.Fatal("invalid target url: " + err.Error())
+	}
+	log.Printf("proxying %s to %s", *listen, targetURL)
+	log.Fatal(http.ListenAndServe(*listen, ssedebug.NewProxy(targetURL, os.Stdout)))
+}
@@ -0,0 +1,98 @@
+package sse
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// WithCoalescing keeps only the most recent message per coalescing key waiting in the queue.
+// A message sent while an earlier one with its key hasn't been written replaces it in the
+// queue, so a backed up connection skips stale values, such as ticker prices and gauges,
+// instead of delivering every intermediate one. Messages without a key are queued as usual.
+func WithCoalescing() Option {
+	return func(o *options) {
+		o.coalescing = true
+	}
+}
+
+// WithCoalesceKey sets the key that the message replaces earlier, unwritten messages with when
+// the connection coalesces its queue
+func (messageBuilder *MessageBuilder) WithCoalesceKey(key string) *MessageBuilder {
+	messageBuilder.message.coalesceKey = key
+	return messageBuilder
+}
+
+// SendCoalesced sends a message with a coalescing key, replacing an earlier message with key
+// that is still waiting to be written when the connection coalesces its queue
+func (connection *Connection) SendCoalesced(key string, message Message) error {
+	message.coalesceKey = key
+	return connection.send(&message)
+}
+
+// Coalesce returns message with a coalescing key, such as for publishing it with a Broker whose
+// connections coalesce their queues
+func Coalesce(key string, message Message) Message {
+	message.coalesceKey = key
+	return message
+}
+
+// coalesce holds message as the latest for its key, queueing a marker for the writer to take
+// it by unless a message with the key is already waiting
+func (connection *Connection) coalesce(ctx context.Context, message *Message) error {
+	key := message.coalesceKey
+	latest := *message
+	message.pooled = nil
+	connection.coalesceMutex.Lock()
+	if replaced, ok := connection.coalesced[key]; ok {
+		connection.coalesced[key] = &latest
+		connection.coalesceMutex.Unlock()
+		if offered, ok := ctx.Value(offerKey{}).(*offering); ok {
+			atomic.StoreInt32(&offered.queued, 1)
+		}
+		replaced.release()
+		replaced.written(ErrMessageDropped)
+		return nil
+	}
+	if connection.coalesced == nil {
+		connection.coalesced = make(map[string]*Message)
+	}
+	connection.coalesced[key] = &latest
+	connection.coalesceMutex.Unlock()
+
+	marker := Message{
+		isCoalesced: true,
+		coalesceKey: key,
+		priority:    message.priority,
+		onWritten: func(err error) {
+			if waiting := connection.takeCoalesced(key); waiting != nil {
+				waiting.release()
+				waiting.written(err)
+			}
+		},
+	}
+	err := connection.push(ctx, &marker)
+	if err == nil {
+		return nil
+	}
+	// a message that replaced this one while the marker was queued is reported the error
+	// instead, since this one was already reported as dropped
+	waiting := connection.takeCoalesced(key)
+	if waiting == nil {
+		return err
+	}
+	waiting.release()
+	if waiting == &latest {
+		return err
+	}
+	waiting.written(err)
+	return nil
+}
+
+// takeCoalesced removes and returns the latest message waiting with key
+func (connection *Connection) takeCoalesced(key string) *Message {
+	connection.coalesceMutex.Lock()
+	defer connection.coalesceMutex.Unlock()
+	message := connection.coalesced[key]
+	delete(connection.coalesced, key)
+	return message
+}
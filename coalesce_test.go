@@ -0,0 +1,157 @@
+package sse
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// blockingResponse is a ResponseRecorder whose first write waits until it is released, so
+// messages sent meanwhile back up in the connection's queue
+type blockingResponse struct {
+	*httptest.ResponseRecorder
+	once    sync.Once
+	blocked chan struct{}
+	release chan struct{}
+}
+
+func newBlockingResponse() *blockingResponse {
+	return &blockingResponse{
+		ResponseRecorder: httptest.NewRecorder(),
+		blocked:          make(chan struct{}),
+		release:          make(chan struct{}),
+	}
+}
+
+func (response *blockingResponse) Write(p []byte) (int, error) {
+	response.once.Do(func() {
+		close(response.blocked)
+		<-response.release
+	})
+	return response.ResponseRecorder.Write(p)
+}
+
+// upgradeBlocked upgrades a request with a queue of 8 messages whose response blocks on the
+// first write, which it waits for the writer to reach
+func upgradeBlocked(t *testing.T, opts ...Option) (*Connection, *blockingResponse) {
+	response := newBlockingResponse()
+	opts = append([]Option{WithQueueSize(8)}, opts...)
+	connection, err := Upgrade(response, httptest.NewRequest("GET", "/", nil), opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	connection.SendString("first")
+	select {
+	case <-response.blocked:
+	case <-time.After(time.Second):
+		t.Fatal("writer never wrote")
+	}
+	return connection, response
+}
+
+// results records the results reported to the callbacks of sent messages by name, failing the
+// test if a callback is called more than once
+type results struct {
+	t        *testing.T
+	mutex    sync.Mutex
+	reported map[string][]error
+}
+
+func newResults(t *testing.T) *results {
+	return &results{t: t, reported: make(map[string][]error)}
+}
+
+// callback returns the callback of the message name
+func (r *results) callback(name string) func(err error) {
+	return func(err error) {
+		r.mutex.Lock()
+		defer r.mutex.Unlock()
+		r.reported[name] = append(r.reported[name], err)
+	}
+}
+
+// anyResult is the result of a message in check that may be reported with any error
+var anyResult = errors.New("any result")
+
+// check fails the test unless each message of want was reported once with its result
+func (r *results) check(want map[string]error) {
+	r.t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for {
+		r.mutex.Lock()
+		complete := len(r.reported) >= len(want)
+		r.mutex.Unlock()
+		if complete || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	// callbacks called twice are usually called again shortly after the first
+	time.Sleep(10 * time.Millisecond)
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for name, err := range want {
+		reported := r.reported[name]
+		if len(reported) != 1 {
+			r.t.Errorf("%s reported %d times: %v", name, len(reported), reported)
+		} else if reported[0] != err && err != anyResult {
+			r.t.Errorf("%s reported %v, want %v", name, reported[0], err)
+		}
+	}
+	for name := range r.reported {
+		if _, ok := want[name]; !ok {
+			r.t.Errorf("%s reported unexpectedly: %v", name, r.reported[name])
+		}
+	}
+}
+
+// passThrough is middleware forwarding every message as is
+func passThrough(next Sender) Sender {
+	return func(ctx context.Context, message Message) error {
+		return next(ctx, message)
+	}
+}
+
+func TestCoalescingReportsEachMessageOnce(t *testing.T) {
+	for name, opts := range map[string][]Option{
+		"queue":      {WithCoalescing()},
+		"middleware": {WithCoalescing(), WithMiddleware(passThrough)},
+	} {
+		t.Run(name, func(t *testing.T) {
+			connection, response := upgradeBlocked(t, opts...)
+			results := newResults(t)
+			for _, value := range []string{"1", "2", "3"} {
+				connection.SendAsync(Coalesce("price", Message{Data: []byte(value)}), results.callback("price "+value))
+			}
+			connection.SendAsync(Coalesce("volume", Message{Data: []byte("9")}), results.callback("volume"))
+			close(response.release)
+			results.check(map[string]error{
+				"price 1": ErrMessageDropped,
+				"price 2": ErrMessageDropped,
+				"price 3": nil,
+				"volume":  nil,
+			})
+			connection.Close()
+			if body := response.Body.String(); body != "data: first\n\ndata: 3\n\ndata: 9\n\n" {
+				t.Errorf("body = %q", body)
+			}
+		})
+	}
+}
+
+func TestCoalescingReportsUnwrittenMessagesOnce(t *testing.T) {
+	connection, response := upgradeBlocked(t, WithCoalescing())
+	results := newResults(t)
+	connection.SendAsync(Coalesce("price", Message{Data: []byte("1")}), results.callback("price 1"))
+	connection.SendAsync(Coalesce("price", Message{Data: []byte("2")}), results.callback("price 2"))
+	// the writer may write the latest price before it sees the abort
+	connection.abort(context.Canceled)
+	close(response.release)
+	results.check(map[string]error{
+		"price 1": ErrMessageDropped,
+		"price 2": anyResult,
+	})
+}
@@ -0,0 +1,51 @@
+package sse
+
+import "context"
+
+// SendAndWait sends a message and waits until it has been written and flushed to the client,
+// returning the error of the write or flush. It gives up with ctx's error when ctx is done
+// first, and returns ErrConnectionClosed if the connection terminates before writing the
+// message, or ErrMessageDropped if it is dropped, including by middleware that returns without
+// forwarding it. The message is flushed even when flushing is coalesced or manual.
+func (connection *Connection) SendAndWait(ctx context.Context, message Message) error {
+	result := make(chan error, 1)
+	message.onWritten = func(err error) {
+		result <- err
+	}
+	if err := connection.sendContext(ctx, &message); err != nil {
+		return err
+	}
+	select {
+	case err := <-result:
+		return err
+	case <-connection.done:
+		select {
+		case err := <-result:
+			return err
+		default:
+			return ErrConnectionClosed
+		}
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// SendAsync queues a message like Send and calls done with the result of writing it, so
+// producers can pipeline sends and still observe each failed write. done is called once, from
+// the connection's writer goroutine unless the message couldn't be queued, and must not block,
+// so like WithDeliveryCallback's callback it must not call Close or SendAndWait.
+// Messages dropped by middleware, or forwarded by it only after it returned, are reported with
+// ErrMessageDropped.
+func (connection *Connection) SendAsync(message Message, done func(err error)) {
+	message.onWritten = done
+	if err := connection.send(&message); err != nil {
+		done(err)
+	}
+}
+
+// written reports the result of writing the message to its sender
+func (message *Message) written(err error) {
+	if message.onWritten != nil {
+		message.onWritten(err)
+	}
+}
@@ -0,0 +1,119 @@
+package sse
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// dropAll is middleware forwarding nothing
+func dropAll(next Sender) Sender {
+	return func(ctx context.Context, message Message) error {
+		return nil
+	}
+}
+
+// fanOut is middleware forwarding every message twice
+func fanOut(next Sender) Sender {
+	return func(ctx context.Context, message Message) error {
+		if err := next(ctx, message); err != nil {
+			return err
+		}
+		return next(ctx, message)
+	}
+}
+
+// forwardLater is middleware forwarding every message after it has returned
+func forwardLater(next Sender) Sender {
+	return func(ctx context.Context, message Message) error {
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			next(ctx, message)
+		}()
+		return nil
+	}
+}
+
+func TestSendAndWait(t *testing.T) {
+	tests := []struct {
+		name   string
+		opts   []Option
+		want   error
+		copies int
+	}{
+		{"written", nil, nil, 1},
+		{"passed through middleware", []Option{WithMiddleware(passThrough)}, nil, 1},
+		{"dropped by middleware", []Option{WithMiddleware(dropAll)}, ErrMessageDropped, 0},
+		{"fanned out by middleware", []Option{WithMiddleware(fanOut)}, nil, 2},
+		{"forwarded after middleware returned", []Option{WithMiddleware(forwardLater)}, ErrMessageDropped, 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			opts := append([]Option{WithQueueSize(8)}, test.opts...)
+			connection, err := Upgrade(recorder, httptest.NewRequest("GET", "/", nil), opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			if err := connection.SendAndWait(ctx, Message{Data: []byte("confirmed")}); err != test.want {
+				t.Errorf("SendAndWait() = %v, want %v", err, test.want)
+			}
+			time.Sleep(20 * time.Millisecond)
+			connection.Close()
+			if copies := strings.Count(recorder.Body.String(), "data: confirmed\n\n"); copies != test.copies {
+				t.Errorf("wrote %d copies, want %d", copies, test.copies)
+			}
+		})
+	}
+}
+
+func TestSendAndWaitGivesUp(t *testing.T) {
+	connection, response := upgradeBlocked(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := connection.SendAndWait(ctx, Message{Data: []byte("late")}); err != context.DeadlineExceeded {
+		t.Errorf("SendAndWait() = %v, want %v", err, context.DeadlineExceeded)
+	}
+	close(response.release)
+	connection.Close()
+	if err := connection.SendAndWait(context.Background(), Message{Data: []byte("closed")}); err != ErrConnectionClosed {
+		t.Errorf("SendAndWait() after Close = %v, want %v", err, ErrConnectionClosed)
+	}
+}
+
+func TestSendAsyncReportsEachMessageOnce(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+		want map[string]error
+	}{
+		{"drop newest", []Option{WithOverflowPolicy(OverflowDropNewest)}, map[string]error{"queued": nil, "overflowed": ErrMessageDropped}},
+		{"drop oldest", []Option{WithOverflowPolicy(OverflowDropOldest)}, map[string]error{"queued": ErrMessageDropped, "overflowed": nil}},
+		{"drop newest through middleware", []Option{WithOverflowPolicy(OverflowDropNewest), WithMiddleware(passThrough)}, map[string]error{"queued": nil, "overflowed": ErrMessageDropped}},
+		{"fanned out", []Option{WithMiddleware(fanOut), WithQueueSize(4)}, map[string]error{"queued": nil, "overflowed": nil}},
+		{"dropped by middleware", []Option{WithMiddleware(dropAll)}, map[string]error{"queued": ErrMessageDropped, "overflowed": ErrMessageDropped}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			response := newBlockingResponse()
+			opts := append([]Option{WithQueueSize(1)}, test.opts...)
+			connection, err := Upgrade(response, httptest.NewRequest("GET", "/", nil), opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			connection.SendComment("first")
+			<-response.blocked
+			results := newResults(t)
+			connection.SendAsync(Message{Data: []byte("queued")}, results.callback("queued"))
+			go connection.SendAsync(Message{Data: []byte("overflowed")}, results.callback("overflowed"))
+			time.Sleep(10 * time.Millisecond)
+			close(response.release)
+			results.check(test.want)
+			connection.Close()
+		})
+	}
+}
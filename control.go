@@ -0,0 +1,79 @@
+package sse
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// System events of the standard control namespace enabled by WithControlEvents
+const (
+	// SysPing is the keepalive event sent for heartbeats, carrying the heartbeat's payload
+	SysPing = "sys:ping"
+	// SysGoAway is sent before the server closes the connection. Its data is a json object
+	// with the reason for closing, such as "limit-reached" or "token-expired", and a detail.
+	SysGoAway = "sys:goaway"
+	// SysReconnect is sent before the server closes the connection for the client to reconnect,
+	// such as at the lifetime set by WithMaxLifetime. Its data is a json object with the reason
+	// and the retry in milliseconds the client should wait before reconnecting.
+	SysReconnect = "sys:reconnect"
+	// SysError reports a server-side error affecting the stream, sent with SendError. Its data
+	// is a json object with the error's message.
+	SysError = "sys:error"
+)
+
+// WithControlEvents makes server features signal the client with the standard sys: control
+// namespace instead of their feature-specific events. Heartbeats set by WithHeartbeat are sent
+// as SysPing events instead of comments, so clients can watch for them. Closing for an
+// exhausted Budget, WithExpiry or a revoked principal sends SysGoAway with the feature's event
+// name as the reason, and WithMaxLifetime sends SysReconnect when it has a retry to reconnect
+// after.
+func WithControlEvents() Option {
+	return func(o *options) {
+		o.controlEvents = true
+	}
+}
+
+// writeHeartbeat writes a heartbeat comment, or a SysPing event with control events, which
+// doesn't count as activity for WithIdleTimeout
+func (w *streamWriter) writeHeartbeat() {
+	if w.options.controlEvents {
+		w.writeMessage(&Message{Event: SysPing, Data: []byte(w.options.heartbeatPayload)})
+	} else {
+		w.writeComment([]byte(w.options.heartbeatPayload))
+	}
+	w.write()
+}
+
+// SendError reports a server-side error affecting the stream to the client, such as a failing
+// source of its events, as a high priority SysError event
+func (connection *Connection) SendError(err error) error {
+	data, _ := json.Marshal(struct {
+		Error string `json:"error"`
+	}{err.Error()})
+	return connection.SendPriority(Message{Event: SysError, Data: data}, PriorityHigh)
+}
+
+// reconnect returns the event and data sent when the server closes the connection for reason,
+// asking the client to reconnect after retry
+func (o *options) reconnect(reason string, retry time.Duration) (string, string) {
+	if !o.controlEvents {
+		return reason, ""
+	}
+	data, _ := json.Marshal(struct {
+		Reason string `json:"reason"`
+		Retry  int64  `json:"retry"`
+	}{reason, retry.Milliseconds()})
+	return SysReconnect, string(data)
+}
+
+// goAway returns the event and data sent when the server closes the connection for reason
+func (o *options) goAway(reason string, detail string) (string, string) {
+	if !o.controlEvents {
+		return reason, detail
+	}
+	data, _ := json.Marshal(struct {
+		Reason string `json:"reason"`
+		Detail string `json:"detail,omitempty"`
+	}{reason, detail})
+	return SysGoAway, string(data)
+}
@@ -0,0 +1,85 @@
+package sse
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestControlEvents(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+		send func(connection *Connection)
+		want string
+	}{
+		{
+			"lifetime with retry",
+			[]Option{WithControlEvents(), WithMaxLifetime(10*time.Millisecond, 1500*time.Millisecond)},
+			nil,
+			"retry: 1500\n\nevent: sys:reconnect\ndata: {\"reason\":\"lifetime-ended\",\"retry\":1500}\n\n",
+		},
+		{
+			"lifetime without retry",
+			[]Option{WithControlEvents(), WithMaxLifetime(10*time.Millisecond, 0)},
+			nil,
+			"event: sys:goaway\ndata: {\"reason\":\"lifetime-ended\"}\n\n",
+		},
+		{
+			"lifetime without control events",
+			[]Option{WithMaxLifetime(10*time.Millisecond, 1500*time.Millisecond)},
+			nil,
+			"retry: 1500\n\nevent: lifetime-ended\ndata: \n\n",
+		},
+		{
+			"error",
+			[]Option{WithControlEvents(), WithMaxLifetime(10*time.Millisecond, 0)},
+			func(connection *Connection) { connection.SendError(errors.New("feed unavailable")) },
+			"event: sys:error\ndata: {\"error\":\"feed unavailable\"}\n\n",
+		},
+		{
+			"revoke",
+			[]Option{WithControlEvents()},
+			func(connection *Connection) { connection.Revoke() },
+			"event: sys:goaway\ndata: {\"reason\":\"auth-revoked\"}\n\n",
+		},
+		{
+			"close with retry",
+			[]Option{WithControlEvents()},
+			func(connection *Connection) { connection.CloseWithRetry(2 * time.Second) },
+			"retry: 2000\n\nevent: sys:reconnect\ndata: {\"reason\":\"shutdown\",\"retry\":2000}\n\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			connection, err := Upgrade(recorder, httptest.NewRequest("GET", "/", nil), test.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if test.send != nil {
+				test.send(connection)
+			}
+			<-connection.Done()
+			if body := recorder.Body.String(); !strings.Contains(body, test.want) {
+				t.Errorf("body = %q, want %q", body, test.want)
+			}
+		})
+	}
+}
+
+func TestLifetimeEventIsValidated(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	_, err := Upgrade(recorder, httptest.NewRequest("GET", "/", nil),
+		WithMaxLifetime(time.Minute, 0), WithLifetimeEvent(Message{Event: "bye\ndata: injected"}))
+	var fieldError *FieldError
+	if !errors.As(err, &fieldError) || fieldError.Field != "event" {
+		t.Errorf("Upgrade() = %v, want an event FieldError", err)
+	}
+	if recorder.Code != http.StatusInternalServerError {
+		t.Errorf("responded %d", recorder.Code)
+	}
+}
@@ -0,0 +1,86 @@
+package sse
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORS configures cross-origin access to a stream with WithCORS
+type CORS struct {
+	// AllowedOrigins are the origins allowed to connect, or "*" to allow any origin
+	AllowedOrigins []string
+	// AllowCredentials allows EventSource's withCredentials to send cookies from the origins
+	// listed in AllowedOrigins. Origins only allowed by "*" are never sent credentials.
+	AllowCredentials bool
+	// AllowedHeaders are the request headers allowed by a preflight request. Polyfills
+	// setting Last-Event-ID and Cache-Control are allowed when empty.
+	AllowedHeaders []string
+	// MaxAge is how long browsers may cache a preflight response
+	MaxAge time.Duration
+}
+
+var defaultCORSHeaders = []string{"Accept", "Cache-Control", "Last-Event-ID", "X-Requested-With"}
+
+// WithCORS allows cross-origin requests from cors.AllowedOrigins. Upgrade refuses requests
+// from other origins with 403 Forbidden and responds to preflight requests itself, returning
+// ErrPreflightHandled.
+func WithCORS(cors CORS) Option {
+	return func(o *options) {
+		o.cors = &cors
+	}
+}
+
+// allowed returns whether origin is one of the allowed origins
+func (c *CORS) allowed(origin string) bool {
+	return c.listed(origin) || c.listed("*")
+}
+
+// listed returns whether origin is listed in the allowed origins
+func (c *CORS) listed(origin string) bool {
+	for _, allowed := range c.AllowedOrigins {
+		if strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// handle sets the CORS headers of request's response. It returns ErrOriginNotAllowed for a
+// disallowed origin and ErrPreflightHandled once it has responded to a preflight request.
+func (c *CORS) handle(writer http.ResponseWriter, request *http.Request) error {
+	writer.Header().Add("Vary", "Origin")
+	origin := request.Header.Get("Origin")
+	if len(origin) == 0 {
+		return nil
+	}
+	if !c.allowed(origin) {
+		return ErrOriginNotAllowed
+	}
+	// browsers reject a wildcard origin on credentialed requests, and echoing any origin with
+	// credentials would let every site read the stream as the user, so only listed origins
+	// are sent credentials
+	if c.AllowCredentials && c.listed(origin) {
+		writer.Header().Set("Access-Control-Allow-Origin", origin)
+		writer.Header().Set("Access-Control-Allow-Credentials", "true")
+	} else if c.listed("*") {
+		writer.Header().Set("Access-Control-Allow-Origin", "*")
+	} else {
+		writer.Header().Set("Access-Control-Allow-Origin", origin)
+	}
+	if request.Method != http.MethodOptions || len(request.Header.Get("Access-Control-Request-Method")) == 0 {
+		return nil
+	}
+	headers := c.AllowedHeaders
+	if len(headers) == 0 {
+		headers = defaultCORSHeaders
+	}
+	writer.Header().Set("Access-Control-Allow-Methods", "GET")
+	writer.Header().Set("Access-Control-Allow-Headers", strings.Join(headers, ", "))
+	if c.MaxAge > 0 {
+		writer.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(c.MaxAge/time.Second)))
+	}
+	writer.WriteHeader(http.StatusNoContent)
+	return ErrPreflightHandled
+}
@@ -0,0 +1,38 @@
+package sse
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCORSCredentials(t *testing.T) {
+	tests := []struct {
+		name        string
+		cors        CORS
+		origin      string
+		allowOrigin string
+		credentials string
+	}{
+		{"listed with credentials", CORS{AllowedOrigins: []string{"https://app.example.com"}, AllowCredentials: true}, "https://app.example.com", "https://app.example.com", "true"},
+		{"wildcard with credentials", CORS{AllowedOrigins: []string{"*"}, AllowCredentials: true}, "https://evil.example.com", "*", ""},
+		{"listed beside wildcard", CORS{AllowedOrigins: []string{"*", "https://app.example.com"}, AllowCredentials: true}, "https://app.example.com", "https://app.example.com", "true"},
+		{"wildcard", CORS{AllowedOrigins: []string{"*"}}, "https://app.example.com", "*", ""},
+		{"listed", CORS{AllowedOrigins: []string{"https://app.example.com"}}, "https://app.example.com", "https://app.example.com", ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			request := httptest.NewRequest("GET", "/", nil)
+			request.Header.Set("Origin", test.origin)
+			if err := test.cors.handle(recorder, request); err != nil {
+				t.Fatal(err)
+			}
+			if got := recorder.Header().Get("Access-Control-Allow-Origin"); got != test.allowOrigin {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, test.allowOrigin)
+			}
+			if got := recorder.Header().Get("Access-Control-Allow-Credentials"); got != test.credentials {
+				t.Errorf("Access-Control-Allow-Credentials = %q, want %q", got, test.credentials)
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package sse
+
+import "strings"
+
+// Events of the Datastar SSE protocol
+const (
+	// DatastarPatchElements patches elements of the page with html
+	DatastarPatchElements = "datastar-patch-elements"
+	// DatastarPatchSignals patches the page's signals with json
+	DatastarPatchSignals = "datastar-patch-signals"
+)
+
+// SendDatastarElements sends a DatastarPatchElements event patching the page with elements.
+// A selector and a patch mode, such as "outer" or "append", are included when not empty,
+// otherwise Datastar morphs the elements matching the fragment's ids.
+func (connection *Connection) SendDatastarElements(selector string, mode string, elements string) error {
+	var data strings.Builder
+	if len(selector) > 0 {
+		data.WriteString("selector " + selector + "\n")
+	}
+	if len(mode) > 0 {
+		data.WriteString("mode " + mode + "\n")
+	}
+	writeDatastarLines(&data, "elements", elements)
+	return connection.BuildMessage().WithEvent(DatastarPatchElements).SendString(data.String())
+}
+
+// SendDatastarSignals sends a DatastarPatchSignals event merging the json of signals into the
+// page's signals
+func (connection *Connection) SendDatastarSignals(signals interface{}) error {
+	payload, err := connection.options.marshalJSON(signals)
+	if err != nil {
+		return err
+	}
+	var data strings.Builder
+	writeDatastarLines(&data, "signals", string(payload))
+	return connection.BuildMessage().WithEvent(DatastarPatchSignals).SendString(data.String())
+}
+
+// writeDatastarLines writes each line of value as a data line starting with name
+func writeDatastarLines(data *strings.Builder, name string, value string) {
+	for i, line := range splitLines([]byte(value)) {
+		if i > 0 {
+			data.WriteByte('\n')
+		}
+		data.WriteString(name + " ")
+		data.Write(line)
+	}
+}
@@ -0,0 +1,147 @@
+package sse
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"iter"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxLineSize is the longest line a Decoder will read
+const maxLineSize = 1024 * 1024
+
+// Event is an event decoded from an SSE stream. Event is empty for events without an event
+// field, which EventSource dispatches as "message".
+type Event struct {
+	ID    string
+	Event string
+	Data  string
+}
+
+// Decoder reads events from an SSE stream, such as a response body, file or recorded capture,
+// following the EventSource parsing rules
+type Decoder struct {
+	scanner     *bufio.Scanner
+	lastEventID string
+	retry       time.Duration
+	started     bool
+	onComment   func(comment string)
+}
+
+// NewDecoder returns a Decoder reading an SSE stream from reader
+func NewDecoder(reader io.Reader) *Decoder {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 4096), maxLineSize)
+	scanner.Split(ScanLines)
+	return &Decoder{scanner: scanner}
+}
+
+// Decode returns the next event of the stream, or io.EOF once the stream has ended. An event
+// left incomplete by the end of the stream is discarded.
+func (decoder *Decoder) Decode() (Event, error) {
+	var event Event
+	var data strings.Builder
+	var hasData bool
+	for decoder.scanner.Scan() {
+		line := decoder.scanner.Text()
+		if !decoder.started {
+			line = strings.TrimPrefix(line, "\ufeff")
+			decoder.started = true
+		}
+		if len(line) == 0 {
+			if !hasData {
+				event.Event = ""
+				continue
+			}
+			event.ID = decoder.lastEventID
+			event.Data = strings.TrimSuffix(data.String(), "\n")
+			return event, nil
+		}
+		if line[0] == ':' {
+			if decoder.onComment != nil {
+				decoder.onComment(strings.TrimPrefix(line[1:], " "))
+			}
+			continue
+		}
+		field, value := line, ""
+		if i := strings.IndexByte(line, ':'); i >= 0 {
+			field, value = line[:i], strings.TrimPrefix(line[i+1:], " ")
+		}
+		switch field {
+		case "event":
+			event.Event = value
+		case "data":
+			data.WriteString(value)
+			data.WriteByte('\n')
+			hasData = true
+		case "id":
+			if !strings.ContainsRune(value, 0) {
+				decoder.lastEventID = value
+			}
+		case "retry":
+			if milliseconds, err := strconv.ParseUint(value, 10, 63); err == nil {
+				decoder.retry = time.Duration(milliseconds) * time.Millisecond
+			}
+		}
+	}
+	if err := decoder.scanner.Err(); err != nil {
+		return Event{}, err
+	}
+	return Event{}, io.EOF
+}
+
+// All returns an iterator over the stream's events. Iteration stops at the end of the stream,
+// or after yielding the error that ended it.
+func (decoder *Decoder) All() iter.Seq2[Event, error] {
+	return func(yield func(Event, error) bool) {
+		for {
+			event, err := decoder.Decode()
+			if err == io.EOF {
+				return
+			}
+			if !yield(event, err) || err != nil {
+				return
+			}
+		}
+	}
+}
+
+// LastEventID returns the last event id set by the stream
+func (decoder *Decoder) LastEventID() string {
+	return decoder.lastEventID
+}
+
+// Retry returns the reconnection time last set by the stream's retry field
+func (decoder *Decoder) Retry() time.Duration {
+	return decoder.retry
+}
+
+// ScanLines is a bufio.SplitFunc for the lines of an event stream, which are terminated by
+// CRLF, LF or CR
+func ScanLines(data []byte, atEOF bool) (int, []byte, error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+	if i := bytes.IndexAny(data, "\r\n"); i >= 0 {
+		if data[i] == '\n' {
+			return i + 1, data[:i], nil
+		}
+		if i+1 < len(data) {
+			if data[i+1] == '\n' {
+				return i + 2, data[:i], nil
+			}
+			return i + 1, data[:i], nil
+		}
+		if atEOF {
+			return i + 1, data[:i], nil
+		}
+		return 0, nil, nil
+	}
+	if atEOF {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
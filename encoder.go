@@ -0,0 +1,45 @@
+package sse
+
+import (
+	"io"
+	"time"
+)
+
+// Encoder writes events to an SSE stream on any io.Writer, such as a file, pipe or test
+// buffer, independent of net/http
+type Encoder struct {
+	writer io.Writer
+	buffer []byte
+}
+
+// NewEncoder returns an Encoder writing an SSE stream to writer
+func NewEncoder(writer io.Writer) *Encoder {
+	return &Encoder{writer: writer}
+}
+
+// Encode writes message to the stream. It returns a FieldError without writing if the id or
+// event field can't be encoded.
+func (encoder *Encoder) Encode(message Message) error {
+	if err := validateFields(&message); err != nil {
+		return err
+	}
+	encoder.buffer = appendMessage(encoder.buffer[:0], &message)
+	return encoder.write()
+}
+
+// EncodeComment writes text to the stream as comment lines, which clients ignore
+func (encoder *Encoder) EncodeComment(text string) error {
+	encoder.buffer = appendLines(encoder.buffer[:0], ": ", []byte(text))
+	return encoder.write()
+}
+
+// EncodeRetry writes a retry field setting the client's reconnection time to delay
+func (encoder *Encoder) EncodeRetry(delay time.Duration) error {
+	encoder.buffer = append(encoder.buffer[:0], retryField(delay)...)
+	return encoder.write()
+}
+
+func (encoder *Encoder) write() error {
+	_, err := encoder.writer.Write(encoder.buffer)
+	return err
+}
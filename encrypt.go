@@ -0,0 +1,63 @@
+package sse
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+)
+
+// WithEncryption encrypts the data of every event sent on the connection with AES-GCM keyed by
+// key, such as a key established with the client at upgrade, for streaming sensitive data
+// through intermediaries that aren't fully trusted. key must be 16, 24 or 32 bytes, otherwise
+// sends fail with the key's error. Clients decrypt events with DecryptEvent.
+func WithEncryption(key []byte) Option {
+	return WithMiddleware(EncryptionMiddleware(key))
+}
+
+// EncryptionMiddleware is the Middleware encrypting events for WithEncryption, for ordering
+// encryption among other middleware. Each event's data is replaced with the base64 encoding of
+// a random nonce followed by the sealed data, authenticated with the event's name.
+func EncryptionMiddleware(key []byte) Middleware {
+	aead, keyErr := newGCM(key)
+	return func(next Sender) Sender {
+		return func(ctx context.Context, message Message) error {
+			if keyErr != nil {
+				return keyErr
+			}
+			nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(message.Data)+aead.Overhead())
+			if _, err := rand.Read(nonce); err != nil {
+				return err
+			}
+			sealed := aead.Seal(nonce, nonce, message.Data, []byte(message.Event))
+			message.Data = base64.StdEncoding.AppendEncode(nil, sealed)
+			return next(ctx, message)
+		}
+	}
+}
+
+// DecryptEvent returns the data of an event encrypted by WithEncryption with key
+func DecryptEvent(key []byte, event Event) ([]byte, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := base64.StdEncoding.DecodeString(event.Data)
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return nil, ErrDecryptionFailed
+	}
+	data, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], []byte(event.Event))
+	if err != nil {
+		return nil, ErrDecryptionFailed
+	}
+	return data, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,98 @@
+package sse
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Envelope is a convention for versioned event payloads, letting long-lived streams evolve
+// the schema of a payload type without breaking older consumers
+type Envelope struct {
+	Version   int             `json:"version"`
+	Type      string          `json:"type"`
+	Timestamp time.Time       `json:"timestamp"`
+	Payload   json.RawMessage `json:"payload"`
+}
+
+// SendEnvelope wraps payload in an Envelope of the builder's event type and sends it as json.
+// Events without an event field have the type "message". Redactions set by WithRedaction
+// are applied to payload, so their paths are relative to the payload rather than the envelope.
+func (messageBuilder *MessageBuilder) SendEnvelope(version int, payload interface{}) error {
+	options := messageBuilder.connection.options
+	data, err := options.marshalJSON(payload)
+	if err != nil {
+		return err
+	}
+	if len(options.redactions) > 0 {
+		if data, err = redact(data, options.redactions); err != nil {
+			return err
+		}
+	}
+	envelopeType := messageBuilder.message.Event
+	if len(envelopeType) == 0 {
+		envelopeType = "message"
+	}
+	// the payload is already redacted, so the envelope is sent as is
+	envelope, err := options.marshalJSON(Envelope{
+		Version:   version,
+		Type:      envelopeType,
+		Timestamp: time.Now(),
+		Payload:   data,
+	})
+	if err != nil {
+		return err
+	}
+	return messageBuilder.SendBytes(envelope)
+}
+
+// Migration upgrades an Envelope's payload from one version to the next
+type Migration func(payload json.RawMessage) (json.RawMessage, error)
+
+// EnvelopeDecoder decodes Envelopes, migrating older payload versions forward with the
+// registered Migrations
+type EnvelopeDecoder struct {
+	migrations map[string]map[int]Migration
+}
+
+// NewEnvelopeDecoder returns an EnvelopeDecoder without any migrations
+func NewEnvelopeDecoder() *EnvelopeDecoder {
+	return &EnvelopeDecoder{migrations: make(map[string]map[int]Migration)}
+}
+
+// RegisterMigration registers migration to upgrade payloads of envelopeType from fromVersion
+// to fromVersion+1
+func (decoder *EnvelopeDecoder) RegisterMigration(envelopeType string, fromVersion int, migration Migration) {
+	if decoder.migrations[envelopeType] == nil {
+		decoder.migrations[envelopeType] = make(map[int]Migration)
+	}
+	decoder.migrations[envelopeType][fromVersion] = migration
+}
+
+// Decode unmarshals an Envelope from an event's data and applies migrations until no
+// migration is registered for the envelope's type and version. If v is not nil, the migrated
+// payload is unmarshalled into it.
+func (decoder *EnvelopeDecoder) Decode(data string, v interface{}) (Envelope, error) {
+	var envelope Envelope
+	if err := json.Unmarshal([]byte(data), &envelope); err != nil {
+		return envelope, err
+	}
+	for {
+		migration, ok := decoder.migrations[envelope.Type][envelope.Version]
+		if !ok {
+			break
+		}
+		payload, err := migration(envelope.Payload)
+		if err != nil {
+			return envelope, fmt.Errorf("migrating %s payload from version %d: %w", envelope.Type, envelope.Version, err)
+		}
+		envelope.Payload = payload
+		envelope.Version++
+	}
+	if v != nil {
+		if err := json.Unmarshal(envelope.Payload, v); err != nil {
+			return envelope, err
+		}
+	}
+	return envelope, nil
+}
@@ -0,0 +1,31 @@
+package sse
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSendEnvelopeRedactsPayload(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	connection, err := Upgrade(recorder, httptest.NewRequest("GET", "/", nil), WithRedaction(DropFields("user.email", "type")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload := map[string]interface{}{
+		"type": "kept",
+		"user": map[string]string{"name": "ada", "email": "ada@example.com"},
+	}
+	if err := connection.BuildMessage().WithEvent("profile").SendEnvelope(2, payload); err != nil {
+		t.Fatal(err)
+	}
+	connection.Close()
+	body := recorder.Body.String()
+	if strings.Contains(body, "ada@example.com") {
+		t.Errorf("payload field not redacted: %q", body)
+	}
+	// paths are relative to the payload, so the envelope's own type field is left alone
+	if !strings.Contains(body, `"type":"profile"`) || !strings.Contains(body, `"payload":{"user":{"name":"ada"}}`) {
+		t.Errorf("envelope = %q", body)
+	}
+}
@@ -0,0 +1,66 @@
+package sse
+
+import "errors"
+
+var (
+	// ErrStreamingUnsupported is returned by Upgrade when the http.ResponseWriter can't flush
+	ErrStreamingUnsupported = errors.New("streaming not supported")
+	// ErrConnectionClosed is returned when sending on a connection that has closed
+	ErrConnectionClosed = errors.New("connection is closed")
+	// ErrSlowClient is the cause of closing a connection whose client can't keep up with the
+	// events sent to it
+	ErrSlowClient = errors.New("client too slow")
+	// ErrMessageDropped is reported to SendAsync and SendAndWait for a message dropped by the
+	// connection's OverflowPolicy or pause buffer
+	ErrMessageDropped = errors.New("message dropped")
+	// ErrBudgetExhausted is the cause of closing a connection that exhausted its Budget
+	ErrBudgetExhausted = errors.New("connection budget exhausted")
+	// ErrConnectionExpired is the cause of closing a connection at the expiry set by WithExpiry
+	ErrConnectionExpired = errors.New("connection expired")
+	// ErrIdleTimeout is the cause of closing a connection that sent no events for the timeout
+	// set by WithIdleTimeout
+	ErrIdleTimeout = errors.New("connection idle timeout")
+	// ErrLifetimeEnded is the cause of closing a connection at the lifetime set by
+	// WithMaxLifetime
+	ErrLifetimeEnded = errors.New("connection lifetime ended")
+	// ErrAuthRevoked is the cause of closing a connection whose principal was revoked with
+	// Broker.Revoke
+	ErrAuthRevoked = errors.New("connection authorization revoked")
+	// ErrPollComplete is the cause of ending a long poll served with WithLongPolling
+	ErrPollComplete = errors.New("long poll complete")
+	// ErrBrokerClosed is returned by Broker.Subscribe once the broker has been shut down
+	ErrBrokerClosed = errors.New("broker closed")
+	// ErrSourceNotAllowed is returned by Upgrade when an IPPolicy refuses the client
+	ErrSourceNotAllowed = errors.New("source address not allowed")
+	// ErrOriginNotAllowed is returned by Upgrade when WithCORS refuses the request's origin
+	ErrOriginNotAllowed = errors.New("origin not allowed")
+	// ErrPreflightHandled is returned by Upgrade once it has responded to a CORS preflight
+	// request, which has no stream to open
+	ErrPreflightHandled = errors.New("cors preflight handled")
+	// ErrTooManyReconnects is returned by Upgrade when a ReconnectMonitor refuses the client
+	ErrTooManyReconnects = errors.New("too many reconnect attempts")
+	// ErrInvalidStreamToken is returned when verifying a malformed or forged stream token
+	ErrInvalidStreamToken = errors.New("invalid stream token")
+	// ErrStreamTokenExpired is returned when verifying a stream token past its expiry
+	ErrStreamTokenExpired = errors.New("stream token expired")
+	// ErrInvalidSignature is returned when verifying an event whose signature doesn't match
+	ErrInvalidSignature = errors.New("invalid event signature")
+	// ErrDecryptionFailed is returned when decrypting an event that wasn't encrypted with the key
+	ErrDecryptionFailed = errors.New("event decryption failed")
+	// ErrMalformedJWT is returned by JWTExpiry for a token that isn't a JWT or whose exp claim
+	// isn't a number
+	ErrMalformedJWT = errors.New("malformed jwt")
+	// ErrMissingJWTExpiry is returned by JWTExpiry for a JWT without an exp claim
+	ErrMissingJWTExpiry = errors.New("jwt has no exp claim")
+	// ErrUnsupportedWebSocket is returned by Upgrade for a websocket handshake other than
+	// version 13's, responding 400 Bad Request
+	ErrUnsupportedWebSocket = errors.New("unsupported websocket handshake")
+	// ErrWebSocketFrameTooLarge is the cause of closing a websocket whose client sent a frame
+	// larger than 64KiB
+	ErrWebSocketFrameTooLarge = errors.New("websocket frame too large")
+	// ErrUnmaskedFrame is the cause of closing a websocket whose client sent a frame without
+	// the masking RFC 6455 requires of clients
+	ErrUnmaskedFrame = errors.New("unmasked websocket frame")
+	// ErrMissingStreamToken is returned when a request has no stream token to verify
+	ErrMissingStreamToken = errors.New("missing stream token")
+)
@@ -0,0 +1,41 @@
+package sse
+
+import (
+	"errors"
+	"net/http"
+)
+
+// EventWriter writes events to an http response synchronously, without the goroutine and
+// channels of a Connection. Errors are returned from each write instead of reported on a
+// channel. An EventWriter must not be used from multiple goroutines.
+type EventWriter struct {
+	encoder    *Encoder
+	controller *http.ResponseController
+}
+
+// NewWriter sets the headers of an event stream on writer's response and returns an
+// EventWriter for writing events to it. The headers are sent with the first write or Flush.
+func NewWriter(writer http.ResponseWriter) *EventWriter {
+	writer.Header().Set("Content-Type", "text/event-stream")
+	writer.Header().Set("Cache-Control", "no-cache")
+	return &EventWriter{
+		encoder:    NewEncoder(writer),
+		controller: http.NewResponseController(writer),
+	}
+}
+
+// WriteEvent writes message to the response. Events are buffered by the response until Flush
+// is called. It returns a FieldError without writing if the id or event field can't be encoded.
+func (w *EventWriter) WriteEvent(message Message) error {
+	return w.encoder.Encode(message)
+}
+
+// Flush sends the events written to the response to the client. It returns
+// ErrStreamingUnsupported if the response can't be flushed.
+func (w *EventWriter) Flush() error {
+	err := w.controller.Flush()
+	if errors.Is(err, http.ErrNotSupported) {
+		return ErrStreamingUnsupported
+	}
+	return err
+}
@@ -0,0 +1,51 @@
+package sse
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TokenExpiredEvent is the event sent before a connection is closed for outliving the expiry
+// set by WithExpiry
+const TokenExpiredEvent = "token-expired"
+
+// WithExpiry closes the connection at expiry, such as the expiry of the credential the client
+// presented, after sending a TokenExpiredEvent. Clients must reconnect with a fresh credential
+// to continue receiving events.
+func WithExpiry(expiry time.Time) Option {
+	return func(o *options) {
+		o.expiry = expiry
+	}
+}
+
+// JWTExpiry returns the time of a JWT's exp claim for use with WithExpiry. The token's
+// signature is not checked and must be verified by the application before it is trusted.
+// JWTExpiry returns ErrMalformedJWT for a token it can't decode, or ErrMissingJWTExpiry for a
+// token without an exp claim.
+func JWTExpiry(token string) (time.Time, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}, ErrMalformedJWT
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: payload", ErrMalformedJWT)
+	}
+	var claims struct {
+		Exp *json.Number `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return time.Time{}, fmt.Errorf("%w: payload", ErrMalformedJWT)
+	}
+	if claims.Exp == nil {
+		return time.Time{}, ErrMissingJWTExpiry
+	}
+	exp, err := claims.Exp.Float64()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: exp claim", ErrMalformedJWT)
+	}
+	return time.Unix(int64(exp), 0), nil
+}
@@ -0,0 +1,38 @@
+package sse
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestJWTExpiry(t *testing.T) {
+	jwt := func(payload string) string {
+		return "e30." + base64.RawURLEncoding.EncodeToString([]byte(payload)) + ".signature"
+	}
+	tests := []struct {
+		name  string
+		token string
+		want  time.Time
+		err   error
+	}{
+		{"exp", jwt(`{"exp":1700000000}`), time.Unix(1700000000, 0), nil},
+		{"not a jwt", "token", time.Time{}, ErrMalformedJWT},
+		{"payload not base64", "e30.!!!.signature", time.Time{}, ErrMalformedJWT},
+		{"payload not json", jwt(`exp`), time.Time{}, ErrMalformedJWT},
+		{"exp not a number", jwt(`{"exp":"soon"}`), time.Time{}, ErrMalformedJWT},
+		{"no exp", jwt(`{"sub":"ada"}`), time.Time{}, ErrMissingJWTExpiry},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			expiry, err := JWTExpiry(test.token)
+			if !errors.Is(err, test.err) {
+				t.Errorf("JWTExpiry() error = %v, want %v", err, test.err)
+			}
+			if !expiry.Equal(test.want) {
+				t.Errorf("JWTExpiry() = %v, want %v", expiry, test.want)
+			}
+		})
+	}
+}
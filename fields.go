@@ -0,0 +1,28 @@
+package sse
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FieldError is returned when sending a message whose id or event field contains a line break
+// or NUL, which would corrupt the stream or be ignored by clients
+type FieldError struct {
+	Field string
+	Value string
+}
+
+func (err *FieldError) Error() string {
+	return fmt.Sprintf("sse %s field %q contains an illegal character", err.Field, err.Value)
+}
+
+// validateFields returns a FieldError if message's id or event can't be sent
+func validateFields(message *Message) error {
+	if strings.ContainsAny(message.ID, "\r\n\x00") {
+		return &FieldError{Field: "id", Value: message.ID}
+	}
+	if strings.ContainsAny(message.Event, "\r\n\x00") {
+		return &FieldError{Field: "event", Value: message.Event}
+	}
+	return nil
+}
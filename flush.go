@@ -0,0 +1,109 @@
+package sse
+
+import "time"
+
+// WithFlushInterval coalesces flushes of the response, flushing events at most once per
+// interval instead of after every write. Comments and the final event of a closing connection
+// are flushed immediately, along with any events written before them.
+func WithFlushInterval(interval time.Duration) Option {
+	return func(o *options) {
+		o.flushInterval = interval
+	}
+}
+
+// WithFlushEvery coalesces flushes of the response until n events have been written. Events
+// are also flushed once the flush interval set by WithFlushInterval elapses, or without one,
+// once no more messages are waiting to be written.
+func WithFlushEvery(n int) Option {
+	return func(o *options) {
+		o.flushEvery = n
+	}
+}
+
+// WithManualFlush stops flushing events after they're written, leaving it to Connection.Flush,
+// so a logically atomic set of events reaches the client in one network write. Events written
+// before the connection closes are flushed.
+func WithManualFlush() Option {
+	return func(o *options) {
+		o.manualFlush = true
+	}
+}
+
+// Flush flushes the events sent before it once they're written. With WithManualFlush, events
+// aren't flushed otherwise.
+func (connection *Connection) Flush() error {
+	if !connection.IsOpen() {
+		return ErrConnectionClosed
+	}
+	select {
+	case connection.messages <- Message{isFlush: true}:
+		return nil
+	case <-connection.done:
+		return ErrConnectionClosed
+	}
+}
+
+// coalescesFlushes returns whether flushes wait for more events to be written
+func (o *options) coalescesFlushes() bool {
+	return o.manualFlush || o.flushInterval > 0 || o.flushEvery > 0
+}
+
+// deferFlush returns whether flushing the events just written should wait for more events,
+// arming the flush interval's timer if it isn't already
+func (w *streamWriter) deferFlush() bool {
+	if w.options.manualFlush {
+		return w.unflushed > 0
+	}
+	if !w.options.coalescesFlushes() || len(w.batched) == 0 {
+		return false
+	}
+	if w.options.flushEvery > 0 && w.unflushed >= w.options.flushEvery {
+		return false
+	}
+	if w.options.flushInterval == 0 {
+		return len(w.messages)+len(w.urgent) > 0
+	}
+	if w.flushDue == nil {
+		if w.flushTimer == nil {
+			w.flushTimer = time.NewTimer(w.options.flushInterval)
+		} else {
+			w.flushTimer.Reset(w.options.flushInterval)
+		}
+		w.flushDue = w.flushTimer.C
+	}
+	return true
+}
+
+// flush flushes the events written to the response
+func (w *streamWriter) flush() error {
+	if w.flushDue != nil {
+		w.flushTimer.Stop()
+		w.flushDue = nil
+	}
+	w.unflushed = 0
+	if timeout := w.connection.writeTimeout(); timeout > 0 {
+		w.controller.SetWriteDeadline(time.Now().Add(timeout))
+	}
+	err := w.controller.Flush()
+	if err == nil {
+		for i := range w.delivered {
+			w.options.audit(AuditDelivery, w.request, &w.delivered[i])
+			for _, onDelivered := range w.options.onDelivered {
+				onDelivered(w.delivered[i].ID)
+			}
+		}
+	}
+	w.delivered = w.delivered[:0]
+	return err
+}
+
+// recordDelivered records the id and event of messages written to the response, for the delivery
+// callbacks and audit run once they've been flushed
+func (w *streamWriter) recordDelivered(messages []Message) {
+	if w.options.auditSink == nil && len(w.options.onDelivered) == 0 {
+		return
+	}
+	for i := range messages {
+		w.delivered = append(w.delivered, Message{ID: messages[i].ID, Event: messages[i].Event})
+	}
+}
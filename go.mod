@@ -1,3 +1,3 @@
 module github.com/eighty4/sse
 
-go 1.7
+go 1.23
@@ -0,0 +1,23 @@
+package sse
+
+import (
+	"context"
+	"net/http"
+)
+
+// Handler returns an http.Handler that upgrades each request with opts and calls stream with
+// the connection. ctx is cancelled once the connection terminates, such as when the client
+// disconnects, so stream should return when ctx is done. The connection is closed when stream
+// returns.
+func Handler(stream func(ctx context.Context, connection *Connection), opts ...Option) http.Handler {
+	options := newOptions(opts)
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		connection, err := Upgrade(writer, request, opts...)
+		if err != nil {
+			options.logger.Println("sse upgrade error: " + err.Error())
+			return
+		}
+		defer connection.Close()
+		stream(connection.Context(), connection)
+	})
+}
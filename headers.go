@@ -0,0 +1,48 @@
+package sse
+
+import "net/http"
+
+// header is a response header set or removed by WithHeader and WithoutHeader
+type header struct {
+	key    string
+	value  string
+	remove bool
+}
+
+// WithHeader sets a response header sent when the stream opens, such as
+// Access-Control-Allow-Origin, overriding Upgrade's default for the same header
+func WithHeader(key string, value string) Option {
+	return func(o *options) {
+		o.headers = append(o.headers, header{key: key, value: value})
+	}
+}
+
+// WithoutHeader removes a response header Upgrade sets by default
+func WithoutHeader(key string) Option {
+	return func(o *options) {
+		o.headers = append(o.headers, header{key: key, remove: true})
+	}
+}
+
+// setHeaders sets the default and configured headers of the stream's response. The
+// Connection header is only set for HTTP/1, as it's illegal in HTTP/2 and later.
+func (o *options) setHeaders(writer http.ResponseWriter, request *http.Request, contentType string) {
+	writer.Header().Set("Content-Type", contentType)
+	writer.Header().Set("Cache-Control", "no-cache")
+	if request.ProtoMajor == 1 {
+		writer.Header().Set("Connection", "keep-alive")
+	}
+	for _, header := range o.headers {
+		if header.remove {
+			writer.Header().Del(header.key)
+		} else {
+			writer.Header().Set(header.key, header.value)
+		}
+	}
+}
+
+// WithProxyBufferingDisabled sets X-Accel-Buffering: no, which stops nginx from buffering
+// the stream's response
+func WithProxyBufferingDisabled() Option {
+	return WithHeader("X-Accel-Buffering", "no")
+}
@@ -0,0 +1,24 @@
+package sse
+
+import "html"
+
+// HtmxMessageEvent is the event name htmx's sse extension matches with sse-swap="message" for
+// events sent without an event field
+const HtmxMessageEvent = "message"
+
+// SendSwap sends an html fragment as an event swapped into elements listening for event with
+// htmx's sse-swap attribute. HtmxMessageEvent sends it without an event field.
+func (connection *Connection) SendSwap(event string, fragment string) error {
+	if event == HtmxMessageEvent {
+		event = ""
+	}
+	return connection.BuildMessage().WithEvent(event).SendString(fragment)
+}
+
+// SendSwapByID sends an html fragment replacing the contents of the element with id, using an
+// htmx out-of-band swap delivered to elements listening for event with sse-swap. The element
+// doesn't need to listen for event itself, but the listener must set hx-swap="none", otherwise
+// htmx swaps the empty remainder of the event into it and clears its contents.
+func (connection *Connection) SendSwapByID(event string, id string, fragment string) error {
+	return connection.SendSwap(event, `<div id="`+html.EscapeString(id)+`" hx-swap-oob="innerHTML">`+fragment+`</div>`)
+}
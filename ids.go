@@ -0,0 +1,37 @@
+package sse
+
+import "strconv"
+
+// WithSequentialIDs assigns events sent without an id the next number of a sequence per
+// connection, so Last-Event-ID resumption works without every call site keeping a counter.
+// The sequence continues from the Last-Event-ID of a reconnecting client when it is a number.
+func WithSequentialIDs() Option {
+	return func(o *options) {
+		o.sequentialIDs = true
+	}
+}
+
+// WithIDGenerator assigns events sent without an id an id returned by generate, such as a
+// UUID or ULID. generate is called from each connection's writer goroutine, so it must be safe
+// for concurrent use when shared by connections.
+func WithIDGenerator(generate func() string) Option {
+	return func(o *options) {
+		o.idGenerator = generate
+	}
+}
+
+// eventIDs returns the generator of ids for events sent without one on a connection resumed
+// from lastEventID, or nil when ids aren't generated
+func (o *options) eventIDs(lastEventID string) func() string {
+	if o.idGenerator != nil {
+		return o.idGenerator
+	}
+	if !o.sequentialIDs {
+		return nil
+	}
+	sequence, _ := strconv.ParseUint(lastEventID, 10, 64)
+	return func() string {
+		sequence++
+		return strconv.FormatUint(sequence, 10)
+	}
+}
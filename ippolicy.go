@@ -0,0 +1,94 @@
+package sse
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// IPPolicy allows or denies upgrades by the client's source address. Addresses matching a
+// Deny network are always refused, and when Allow is not empty only addresses matching an
+// Allow network are accepted.
+type IPPolicy struct {
+	Allow []*net.IPNet
+	Deny  []*net.IPNet
+	// TrustForwardedFor uses the address appended to X-Forwarded-For by the nearest proxy
+	// instead of the connection's remote address. Only enable it behind a proxy that sets
+	// the header, otherwise clients can choose their own source address.
+	TrustForwardedFor bool
+}
+
+// NewIPPolicy parses allow and deny lists of CIDR networks or single IP addresses
+func NewIPPolicy(allow []string, deny []string) (*IPPolicy, error) {
+	policy := &IPPolicy{}
+	var err error
+	if policy.Allow, err = parseNetworks(allow); err != nil {
+		return nil, err
+	}
+	if policy.Deny, err = parseNetworks(deny); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// Allowed returns whether the policy accepts the request's source address
+func (policy *IPPolicy) Allowed(request *http.Request) bool {
+	ip := policy.sourceIP(request)
+	if ip == nil {
+		return false
+	}
+	for _, network := range policy.Deny {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	if len(policy.Allow) == 0 {
+		return true
+	}
+	for _, network := range policy.Allow {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func (policy *IPPolicy) sourceIP(request *http.Request) net.IP {
+	if policy.TrustForwardedFor {
+		// the last hop is the one added by the trusted proxy, which may append its own header
+		// line after any the client sent
+		if values := request.Header.Values("X-Forwarded-For"); len(values) > 0 {
+			hops := strings.Split(values[len(values)-1], ",")
+			return net.ParseIP(strings.TrimSpace(hops[len(hops)-1]))
+		}
+	}
+	return net.ParseIP(remoteIP(request))
+}
+
+// WithIPPolicy refuses upgrades from source addresses the policy does not allow by
+// responding 403 Forbidden before the stream is opened
+func WithIPPolicy(policy *IPPolicy) Option {
+	return func(o *options) {
+		o.ipPolicy = policy
+	}
+}
+
+func parseNetworks(addresses []string) ([]*net.IPNet, error) {
+	networks := make([]*net.IPNet, 0, len(addresses))
+	for _, address := range addresses {
+		if !strings.Contains(address, "/") {
+			// IPv4-mapped IPv6 addresses are normalized so they match the IPv4 address
+			if ip := net.ParseIP(address); ip != nil && ip.To4() != nil {
+				address = ip.To4().String() + "/32"
+			} else {
+				address += "/128"
+			}
+		}
+		_, network, err := net.ParseCIDR(address)
+		if err != nil {
+			return nil, err
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
@@ -0,0 +1,39 @@
+package sse
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIPPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		allow   []string
+		deny    []string
+		remote  string
+		allowed bool
+	}{
+		{"allowed address", []string{"10.0.0.1"}, nil, "10.0.0.1:1234", true},
+		{"other address", []string{"10.0.0.1"}, nil, "10.0.0.2:1234", false},
+		{"allowed network", []string{"10.0.0.0/8"}, nil, "10.1.2.3:1234", true},
+		{"denied within allowed", []string{"10.0.0.0/8"}, []string{"10.0.0.1"}, "10.0.0.1:1234", false},
+		{"mapped address", []string{"::ffff:10.0.0.1"}, nil, "10.0.0.1:1234", true},
+		{"mapped address only", []string{"::ffff:10.0.0.1"}, nil, "10.0.0.2:1234", false},
+		{"mapped address from ipv6", []string{"::ffff:10.0.0.1"}, nil, "[2001:db8::1]:1234", false},
+		{"ipv6 address", []string{"2001:db8::1"}, nil, "[2001:db8::1]:1234", true},
+		{"ipv6 neighbor", []string{"2001:db8::1"}, nil, "[2001:db8::2]:1234", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			policy, err := NewIPPolicy(test.allow, test.deny)
+			if err != nil {
+				t.Fatal(err)
+			}
+			request := httptest.NewRequest("GET", "/", nil)
+			request.RemoteAddr = test.remote
+			if allowed := policy.Allowed(request); allowed != test.allowed {
+				t.Errorf("Allowed() = %v, want %v", allowed, test.allowed)
+			}
+		})
+	}
+}
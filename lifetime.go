@@ -0,0 +1,64 @@
+package sse
+
+import "time"
+
+// WithIdleTimeout closes the connection with ErrIdleTimeout when no events have been sent for
+// timeout. Comments and heartbeats don't count as activity.
+func WithIdleTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.idleTimeout = timeout
+	}
+}
+
+// LifetimeEndedEvent is the event sent before a connection is closed for reaching the lifetime
+// set by WithMaxLifetime
+const LifetimeEndedEvent = "lifetime-ended"
+
+// WithMaxLifetime recycles the connection once it has been open for lifetime, such as for
+// moving clients to new backends during a rolling deploy. Queued messages are delivered, then
+// a LifetimeEndedEvent and a retry field of retry are sent, and the connection is closed with
+// ErrLifetimeEnded. A retry of 0 leaves the client's reconnection time unchanged.
+func WithMaxLifetime(lifetime time.Duration, retry time.Duration) Option {
+	return func(o *options) {
+		o.maxLifetime = lifetime
+		o.lifetimeRetry = retry
+	}
+}
+
+// WithLifetimeEvent replaces the LifetimeEndedEvent sent when a connection reaches the lifetime
+// set by WithMaxLifetime with message, which is passed through the connection's middleware.
+// Upgrade responds 500 Internal Server Error and returns a FieldError if the message's id or
+// event field can't be encoded.
+func WithLifetimeEvent(message Message) Option {
+	return func(o *options) {
+		o.lifetimeEvent = &message
+	}
+}
+
+// endLifetime delivers the queued messages and the final event of a connection that reached
+// its lifetime
+func (w *streamWriter) endLifetime() error {
+	if err := w.deliverQueued(); err != nil {
+		return err
+	}
+	if w.options.lifetimeRetry > 0 && !w.ndjson {
+		w.batch.WriteString(retryField(w.options.lifetimeRetry))
+	}
+	if w.options.lifetimeEvent != nil {
+		if event := w.connection.transform(*w.options.lifetimeEvent); event != nil {
+			w.writeMessage(event)
+		}
+		w.write()
+	} else if w.options.lifetimeRetry > 0 {
+		w.closeWith(w.options.reconnect(LifetimeEndedEvent, w.options.lifetimeRetry))
+	} else {
+		w.closeWith(w.options.goAway(LifetimeEndedEvent, ""))
+	}
+	return ErrLifetimeEnded
+}
+
+// idle returns how long until the connection times out for being idle, or a negative
+// duration once it has
+func (w *streamWriter) idle() time.Duration {
+	return w.options.idleTimeout - time.Since(w.lastEvent)
+}
@@ -0,0 +1,27 @@
+package sse
+
+import (
+	"net/http"
+	"time"
+)
+
+// LongPollTransport is the value of the transport query parameter with which clients behind
+// proxies that break streaming request a long poll
+const LongPollTransport = "longpoll"
+
+// WithLongPolling serves requests with a transport=longpoll query parameter as long polls
+// instead of streams. The response ends once the first events sent on the connection have
+// been delivered, or after timeout without any, terminating the connection with
+// ErrPollComplete. A snapshot sent by WithSnapshot is delivered with the first events instead
+// of completing the poll by itself. Clients poll again with a lastEventId query parameter, so handlers resume
+// from LastEventID as they would for a reconnecting EventSource.
+func WithLongPolling(timeout time.Duration) Option {
+	return func(o *options) {
+		o.longPollTimeout = timeout
+	}
+}
+
+// wantsLongPoll returns whether request should be served as a long poll
+func (o *options) wantsLongPoll(request *http.Request) bool {
+	return o.longPollTimeout > 0 && request.URL.Query().Get("transport") == LongPollTransport
+}
@@ -0,0 +1,57 @@
+package sse
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLongPollWithSnapshot(t *testing.T) {
+	opts := []Option{
+		WithLongPolling(time.Second),
+		WithSnapshot(func(*http.Request) (interface{}, error) { return "state", nil }),
+	}
+	for _, target := range []string{"/?transport=longpoll", "/?transport=longpoll&lastEventId=1"} {
+		recorder := httptest.NewRecorder()
+		connection, err := Upgrade(recorder, httptest.NewRequest("GET", target, nil), opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		go func() {
+			time.Sleep(20 * time.Millisecond)
+			connection.Send(Message{ID: "2", Event: "update", Data: []byte("live")})
+		}()
+		select {
+		case <-connection.Done():
+		case <-time.After(500 * time.Millisecond):
+			t.Fatalf("poll of %s didn't complete with the first event", target)
+		}
+		body := recorder.Body.String()
+		if !strings.Contains(body, "id: 2\nevent: update\ndata: live\n\n") {
+			t.Errorf("poll of %s didn't return the event: %q", target, body)
+		}
+		if snapshot := strings.Contains(body, "event: "+SnapshotEvent); snapshot != strings.HasSuffix(target, "longpoll") {
+			t.Errorf("poll of %s returned snapshot %v: %q", target, snapshot, body)
+		}
+	}
+}
+
+func TestLongPollTimeout(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	connection, err := Upgrade(recorder, httptest.NewRequest("GET", "/?transport=longpoll", nil), WithLongPolling(20*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	reason := make(chan error, 1)
+	connection.OnClose(func(err error) { reason <- err })
+	select {
+	case err := <-reason:
+		if err != ErrPollComplete {
+			t.Errorf("poll closed with %v, want %v", err, ErrPollComplete)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("poll didn't time out")
+	}
+}
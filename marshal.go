@@ -0,0 +1,23 @@
+package sse
+
+import "encoding/json"
+
+// JSONMarshal marshals the json payloads of connections upgraded without WithJSONMarshaler,
+// such as for SendJson and snapshots. It defaults to encoding/json and may be replaced with a
+// faster compatible marshaler during initialization, before any connection is upgraded.
+var JSONMarshal func(v interface{}) ([]byte, error) = json.Marshal
+
+// WithJSONMarshaler marshals the connection's json payloads with marshal instead of JSONMarshal
+func WithJSONMarshaler(marshal func(v interface{}) ([]byte, error)) Option {
+	return func(o *options) {
+		o.jsonMarshal = marshal
+	}
+}
+
+// marshalJSON marshals a json payload with the connection's marshaler
+func (o *options) marshalJSON(v interface{}) ([]byte, error) {
+	if o.jsonMarshal != nil {
+		return o.jsonMarshal(v)
+	}
+	return JSONMarshal(v)
+}
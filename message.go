@@ -0,0 +1,26 @@
+package sse
+
+import "io"
+
+// WriteTo writes the message's SSE encoding to writer, so events can be encoded outside of a
+// Connection such as in proxies and tests. It returns a FieldError without writing if the id
+// or event field can't be encoded.
+func (message Message) WriteTo(writer io.Writer) (int64, error) {
+	if err := validateFields(&message); err != nil {
+		return 0, err
+	}
+	buffer := dataPool.Get().(*[]byte)
+	*buffer = appendMessage((*buffer)[:0], &message)
+	n, err := writer.Write(*buffer)
+	if cap(*buffer) <= maxPooledData {
+		dataPool.Put(buffer)
+	}
+	return int64(n), err
+}
+
+// AppendTo appends the message's SSE encoding to dst and returns the extended slice, such as
+// for encoding an event once and writing it to many clients. The id and event fields aren't
+// checked, so they must not contain line breaks or NUL.
+func (message Message) AppendTo(dst []byte) []byte {
+	return appendMessage(dst, &message)
+}
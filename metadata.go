@@ -0,0 +1,23 @@
+package sse
+
+// WithMetadata sets metadata on the connection when it's upgraded, such as the user or tenant
+// the stream belongs to
+func WithMetadata(key string, value interface{}) Option {
+	return func(o *options) {
+		if o.metadata == nil {
+			o.metadata = map[string]interface{}{}
+		}
+		o.metadata[key] = value
+	}
+}
+
+// Set attaches value to the connection under key, so code managing many connections can tag
+// them with application state
+func (connection *Connection) Set(key string, value interface{}) {
+	connection.metadata.Store(key, value)
+}
+
+// Get returns the value attached to the connection under key
+func (connection *Connection) Get(key string) (interface{}, bool) {
+	return connection.metadata.Load(key)
+}
@@ -0,0 +1,104 @@
+package sse
+
+import (
+	"context"
+	"sync"
+)
+
+// Sender sends a message, returning an error if it couldn't be queued for the client
+type Sender func(ctx context.Context, message Message) error
+
+// Middleware wraps the sending of every message on a connection, such as for enriching
+// payloads, redacting fields, adding timestamps or recording metrics. It may change the message
+// before calling next or drop it by returning without calling next, and may call next more than
+// once, such as to fan a message out, or later from another goroutine. Messages are validated
+// before they are passed to middleware, so validators see the message as it was sent.
+type Middleware func(next Sender) Sender
+
+// WithMiddleware wraps the connection's sends with middleware. The first middleware is the
+// outermost, seeing each message before the others. The snapshot and lifetime event the
+// connection sends itself are passed to middleware too, but comments aren't.
+func WithMiddleware(middleware ...Middleware) Option {
+	return func(o *options) {
+		o.middleware = append(o.middleware, middleware...)
+	}
+}
+
+// chainMiddleware returns the Sender passing messages through the connection's middleware
+// before queuing them, or nil without middleware
+func (connection *Connection) chainMiddleware() Sender {
+	middleware := connection.options.middleware
+	if len(middleware) == 0 {
+		return nil
+	}
+	sender := Sender(func(ctx context.Context, message Message) error {
+		// only the first message forwarded before the middleware returned is confirmed
+		if message.forwarding != nil && !message.forwarding.forward() {
+			message.onWritten = nil
+		}
+		return connection.queue(ctx, &message)
+	})
+	for i := len(middleware) - 1; i >= 0; i-- {
+		sender = middleware[i](sender)
+	}
+	return sender
+}
+
+// transform passes a message the writer sends itself, such as the snapshot, through the
+// connection's middleware without queuing it, returning the first message forwarded before the
+// middleware returned, or nil if the middleware dropped it
+func (connection *Connection) transform(message Message) *Message {
+	if len(connection.options.middleware) == 0 {
+		return &message
+	}
+	var mutex sync.Mutex
+	var transformed *Message
+	settled := false
+	sender := Sender(func(ctx context.Context, message Message) error {
+		mutex.Lock()
+		defer mutex.Unlock()
+		if transformed == nil && !settled {
+			transformed = &message
+		}
+		return nil
+	})
+	middleware := connection.options.middleware
+	for i := len(middleware) - 1; i >= 0; i-- {
+		sender = middleware[i](sender)
+	}
+	err := sender(connection.ctx, message)
+	mutex.Lock()
+	defer mutex.Unlock()
+	settled = true
+	if err != nil {
+		return nil
+	}
+	return transformed
+}
+
+// forwarding tracks whether middleware forwarded a confirmed message to be queued, so its
+// sender is told when the middleware dropped it instead
+type forwarding struct {
+	mutex     sync.Mutex
+	forwarded bool
+	settled   bool
+}
+
+// forward returns whether the message is the first forwarded before the middleware returned
+func (forwarding *forwarding) forward() bool {
+	forwarding.mutex.Lock()
+	defer forwarding.mutex.Unlock()
+	if forwarding.forwarded || forwarding.settled {
+		return false
+	}
+	forwarding.forwarded = true
+	return true
+}
+
+// settle returns whether the middleware returned without forwarding the message
+func (forwarding *forwarding) settle() bool {
+	forwarding.mutex.Lock()
+	defer forwarding.mutex.Unlock()
+	forwarding.settled = true
+	return !forwarding.forwarded
+}
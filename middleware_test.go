@@ -0,0 +1,46 @@
+package sse
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTrySendPassesThroughMiddleware(t *testing.T) {
+	for _, opt := range []Option{WithEncryption(make([]byte, 32)), WithSigning([]byte("key"))} {
+		recorder := httptest.NewRecorder()
+		connection, err := Upgrade(recorder, httptest.NewRequest("GET", "/", nil), opt, WithQueueSize(4))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if queued, err := connection.TrySend(Message{Event: "secret", Data: []byte("SECRET-TRYSEND")}); !queued || err != nil {
+			t.Errorf("TrySend() = %v, %v", queued, err)
+		}
+		connection.Close()
+		if body := recorder.Body.String(); !strings.Contains(body, "event: secret") || strings.Contains(body, "data: SECRET-TRYSEND") {
+			t.Errorf("middleware not applied to TrySend: %q", body)
+		}
+	}
+}
+
+func TestSnapshotAndLifetimeEventPassThroughMiddleware(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	connection, err := Upgrade(recorder, httptest.NewRequest("GET", "/", nil),
+		WithEncryption(make([]byte, 32)),
+		WithSnapshot(func(*http.Request) (interface{}, error) { return "SECRET-SNAPSHOT", nil }),
+		WithMaxLifetime(10*time.Millisecond, 0),
+		WithLifetimeEvent(Message{Event: "bye", Data: []byte("SECRET-LIFETIME")}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-connection.Done()
+	body := recorder.Body.String()
+	if !strings.Contains(body, "event: "+SnapshotEvent) || !strings.Contains(body, "event: bye") {
+		t.Fatalf("missing snapshot or lifetime event: %q", body)
+	}
+	if strings.Contains(body, "SECRET") {
+		t.Errorf("middleware not applied to snapshot or lifetime event: %q", body)
+	}
+}
@@ -0,0 +1,44 @@
+package sse
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// ndjsonType is the media type of newline-delimited json
+const ndjsonType = "application/x-ndjson"
+
+// WithNDJSON streams newline-delimited json to requests accepting application/x-ndjson but not
+// text/event-stream, so one handler serves both EventSource clients and consumers preferring
+// json lines. Each event is written as a json object with its id, event and data, embedding
+// data that is valid json as is. Comments, heartbeats and retry fields aren't written.
+func WithNDJSON() Option {
+	return func(o *options) {
+		o.ndjson = true
+	}
+}
+
+// wantsNDJSON returns whether request should be streamed newline-delimited json
+func (o *options) wantsNDJSON(request *http.Request) bool {
+	return o.ndjson && accepts(request, ndjsonType) && !accepts(request, "text/event-stream")
+}
+
+// ndjsonEvent is the json line written for an event
+type ndjsonEvent struct {
+	ID    string      `json:"id,omitempty"`
+	Event string      `json:"event,omitempty"`
+	Data  interface{} `json:"data"`
+}
+
+// appendNDJSON appends the json line of a message's id, event and data attributes to dst
+func appendNDJSON(dst []byte, message *Message) []byte {
+	event := ndjsonEvent{ID: message.ID, Event: message.Event, Data: string(message.Data)}
+	if json.Valid(message.Data) {
+		event.Data = json.RawMessage(message.Data)
+	}
+	line, err := json.Marshal(event)
+	if err != nil {
+		return dst
+	}
+	return append(append(dst, line...), '\n')
+}
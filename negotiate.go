@@ -0,0 +1,97 @@
+package sse
+
+import (
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// Negotiate returns a handler serving one route in two consumption modes. Requests accepting
+// text/event-stream are upgraded with opts and passed to stream, which should return once it
+// is done streaming. Other requests are responded to with the json of the current state
+// returned by snapshot.
+func Negotiate(snapshot func(request *http.Request) (interface{}, error), stream func(connection *Connection, request *http.Request), opts ...Option) http.Handler {
+	options := newOptions(opts)
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if !options.wantsStream(request) {
+			state, err := snapshot(request)
+			if err != nil {
+				http.Error(writer, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			data, err := options.marshalJSON(state)
+			if err != nil {
+				http.Error(writer, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			writer.Header().Set("Content-Type", "application/json")
+			if _, err := writer.Write(data); err != nil {
+				options.logger.Println("sse snapshot write error: " + err.Error())
+			}
+			return
+		}
+		connection, err := Upgrade(writer, request, opts...)
+		if err != nil {
+			options.logger.Println("sse upgrade error: " + err.Error())
+			return
+		}
+		stream(connection, request)
+	})
+}
+
+// RequestError is returned by Upgrade when WithRequestValidation refuses a request that
+// isn't an EventSource request
+type RequestError struct {
+	// Status is the status code the request was refused with
+	Status int
+	// Reason describes what was wrong with the request
+	Reason string
+}
+
+func (e *RequestError) Error() string {
+	return e.Reason
+}
+
+// WithRequestValidation refuses requests that aren't an EventSource's, responding 405 Method
+// Not Allowed to requests other than GET and 406 Not Acceptable to requests that don't accept
+// text/event-stream. Upgrade returns a *RequestError for a refused request.
+func WithRequestValidation() Option {
+	return func(o *options) {
+		o.validateRequest = true
+	}
+}
+
+// checkRequest returns a *RequestError when request isn't an EventSource request
+func (o *options) checkRequest(writer http.ResponseWriter, request *http.Request) *RequestError {
+	if request.Method != http.MethodGet {
+		writer.Header().Set("Allow", http.MethodGet)
+		return &RequestError{
+			Status: http.StatusMethodNotAllowed,
+			Reason: "event streams are requested with GET, not " + request.Method,
+		}
+	}
+	if !o.wantsStream(request) {
+		return &RequestError{
+			Status: http.StatusNotAcceptable,
+			Reason: "event streams are served as text/event-stream, which the Accept header doesn't include",
+		}
+	}
+	return nil
+}
+
+// acceptsEventStream returns whether the request's Accept header includes text/event-stream
+func acceptsEventStream(request *http.Request) bool {
+	return accepts(request, "text/event-stream")
+}
+
+// accepts returns whether the request's Accept header includes contentType
+func accepts(request *http.Request, contentType string) bool {
+	for _, accept := range request.Header.Values("Accept") {
+		for _, mediaRange := range strings.Split(accept, ",") {
+			if mediaType, _, err := mime.ParseMediaType(mediaRange); err == nil && mediaType == contentType {
+				return true
+			}
+		}
+	}
+	return false
+}
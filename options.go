@@ -0,0 +1,135 @@
+package sse
+
+import (
+	"log"
+	"net/http"
+	"time"
+)
+
+// Option configures a Connection when upgrading a request. Upgrade accepts any number of
+// Options, so new behavior can be added without changing its signature.
+type Option func(*options)
+
+type options struct {
+	maxBytes                int64
+	maxEvents               int64
+	auditSink               AuditSink
+	auditDeliverySampleRate float64
+	ipPolicy                *IPPolicy
+	redactions              []Redaction
+	reconnectMonitor        *ReconnectMonitor
+	expiry                  time.Time
+	retryAfter              time.Duration
+	pauseBufferSize         int
+	snapshot                func(request *http.Request) (interface{}, error)
+	onDelivered             []func(id string)
+	controlEvents           bool
+	validators              map[string]Validator
+	validationMode          ValidationMode
+	polyfill                bool
+	heartbeatInterval       time.Duration
+	heartbeatPayload        string
+	reconnectDelay          time.Duration
+	queueSize               int
+	overflowPolicy          OverflowPolicy
+	writeTimeout            time.Duration
+	logger                  *log.Logger
+	onClose                 []func(reason error)
+	onUpgrade               []func(connection *Connection)
+	headers                 []header
+	cors                    *CORS
+	validateRequest         bool
+	padding                 bool
+	metadata                map[string]interface{}
+	principal               func(request *http.Request) string
+	tags                    func(request *http.Request) []string
+	flushInterval           time.Duration
+	flushEvery              int
+	manualFlush             bool
+	sequentialIDs           bool
+	idGenerator             func() string
+	idleTimeout             time.Duration
+	maxLifetime             time.Duration
+	lifetimeRetry           time.Duration
+	lifetimeEvent           *Message
+	reconnectWindow         time.Duration
+	ndjson                  bool
+	longPollTimeout         time.Duration
+	websocket               bool
+	payloadEncoder          PayloadEncoder
+	jsonMarshal             func(v interface{}) ([]byte, error)
+	middleware              []Middleware
+	rateLimits              map[string]rateLimit
+	coalescing              bool
+	topicSeparator          string
+	topicPropagation        TopicPropagation
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{logger: log.Default()}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithDeliveryCallback calls onDelivered with the id of each event after it has been written
+// and flushed to the client, so applications can track what a connection was delivered up to.
+// onDelivered is called from the connection's writer goroutine and should return quickly. It
+// must not call Close or SendAndWait, or Send on a full queue, which wait for the writer
+// goroutine and would deadlock; call them from another goroutine instead.
+func WithDeliveryCallback(onDelivered func(id string)) Option {
+	return func(o *options) {
+		o.onDelivered = append(o.onDelivered, onDelivered)
+	}
+}
+
+// WithHeartbeat sends payload as a comment line every interval, keeping proxies and load
+// balancers from timing out idle streams. Heartbeats stop when the connection closes.
+func WithHeartbeat(interval time.Duration, payload string) Option {
+	return func(o *options) {
+		o.heartbeatInterval = interval
+		o.heartbeatPayload = payload
+	}
+}
+
+// WithReconnectDelay sends a retry field as soon as the stream opens, setting how long clients
+// wait before reconnecting after the connection is lost
+func WithReconnectDelay(delay time.Duration) Option {
+	return func(o *options) {
+		o.reconnectDelay = delay
+	}
+}
+
+// WithQueueSize buffers up to size messages waiting to be written, so bursty producers aren't
+// blocked by slow flushes. Without it, every send waits for the connection's writer.
+func WithQueueSize(size int) Option {
+	return func(o *options) {
+		o.queueSize = size
+	}
+}
+
+// WithWriteTimeout sets a deadline of timeout on each write to the client with
+// http.ResponseController, so a hung TCP connection can't pin the connection's writer. A
+// write missing its deadline closes the connection.
+func WithWriteTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.writeTimeout = timeout
+	}
+}
+
+// WithLogger sends the connection's log output, such as write errors that couldn't be
+// delivered to Errors, to logger instead of the standard logger
+func WithLogger(logger *log.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// WithOnClose registers onClose as a callback of the upgraded connection, as with
+// Connection.OnClose
+func WithOnClose(onClose func(reason error)) Option {
+	return func(o *options) {
+		o.onClose = append(o.onClose, onClose)
+	}
+}
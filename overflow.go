@@ -0,0 +1,128 @@
+package sse
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// OverflowPolicy sets what happens to a message sent when the connection's queue is full
+type OverflowPolicy int
+
+const (
+	// OverflowBlock waits for room in the queue, or fails TrySend without waiting
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDropOldest discards the oldest queued message to make room
+	OverflowDropOldest
+	// OverflowDropNewest discards the message being sent
+	OverflowDropNewest
+	// OverflowClose closes the connection with ErrSlowClient
+	OverflowClose
+)
+
+// WithOverflowPolicy sets how sends are handled when the queue set by WithQueueSize is full.
+// With any policy other than OverflowBlock, sends never wait for the connection's writer.
+func WithOverflowPolicy(policy OverflowPolicy) Option {
+	return func(o *options) {
+		o.overflowPolicy = policy
+	}
+}
+
+// TrySend sends a message without waiting for room in the connection's queue, returning
+// whether the message was queued. When the queue is full, the connection's OverflowPolicy
+// decides whether room is made by dropping the oldest message, the message is dropped, or the
+// connection is closed with ErrSlowClient. Like Send, the message goes through the connection's
+// middleware, and isn't queued when middleware drops it.
+func (connection *Connection) TrySend(message Message) (bool, error) {
+	ctx, offered := offerContext()
+	if err := connection.sendContext(ctx, &message); err != nil {
+		return false, err
+	}
+	return offered.isQueued(), nil
+}
+
+// offerKey is the context key of an offering
+type offerKey struct{}
+
+// offering records whether a message sent with the context returned by offerContext was
+// queued. Sends with the context go through middleware, but never wait for room in the queue.
+type offering struct {
+	queued int32
+}
+
+// offerContext returns a context for sending a message without waiting for room in the queue,
+// and the offering recording whether it was queued
+func offerContext() (context.Context, *offering) {
+	offered := &offering{}
+	return context.WithValue(context.Background(), offerKey{}, offered), offered
+}
+
+// isQueued returns whether the offered message was queued
+func (offered *offering) isQueued() bool {
+	return atomic.LoadInt32(&offered.queued) == 1
+}
+
+// offer queues message without blocking, applying the overflow policy if the queue is full
+func (connection *Connection) offer(message *Message) (bool, error) {
+	lane := connection.lane(message)
+	if !connection.limitsQueue(lane) {
+		select {
+		case lane <- *message:
+			return true, nil
+		default:
+		}
+	}
+	switch connection.options.overflowPolicy {
+	case OverflowDropOldest:
+		// a flush requested with Flush is queued again, flushing the messages before it once
+		// they're written even though it moved behind them
+		if dropOldest(lane) {
+			select {
+			case lane <- Message{isFlush: true}:
+			default:
+			}
+		}
+		if connection.limitsQueue(lane) {
+			return false, nil
+		}
+		select {
+		case lane <- *message:
+			return true, nil
+		default:
+			return false, nil
+		}
+	case OverflowClose:
+		connection.abort(ErrSlowClient)
+		return false, ErrSlowClient
+	default:
+		return false, nil
+	}
+}
+
+// dropOldest discards the oldest message waiting in lane, returning whether it took flush
+// markers from ahead of it, which aren't messages to drop
+func dropOldest(lane chan Message) bool {
+	flushed := false
+	for {
+		select {
+		case dropped := <-lane:
+			if dropped.isFlush {
+				flushed = true
+				continue
+			}
+			dropped.release()
+			dropped.written(ErrMessageDropped)
+			return flushed
+		default:
+			return flushed
+		}
+	}
+}
+
+// abort stops the connection's writer without delivering queued messages, terminating the
+// connection with reason
+func (connection *Connection) abort(reason error) {
+	select {
+	case connection.aborted <- reason:
+	default:
+	}
+}
@@ -0,0 +1,67 @@
+package sse
+
+import (
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+// flushCountingResponse is a blockingResponse counting its flushes
+type flushCountingResponse struct {
+	*blockingResponse
+	flushes int32
+}
+
+func (response *flushCountingResponse) Flush() {
+	atomic.AddInt32(&response.flushes, 1)
+	response.blockingResponse.Flush()
+}
+
+func TestTrySend(t *testing.T) {
+	tests := []struct {
+		policy OverflowPolicy
+		queued bool
+		err    error
+	}{
+		{OverflowBlock, false, nil},
+		{OverflowDropNewest, false, nil},
+		{OverflowDropOldest, true, nil},
+		{OverflowClose, false, ErrSlowClient},
+	}
+	for _, test := range tests {
+		connection, response := upgradeBlocked(t, WithOverflowPolicy(test.policy), WithQueueSize(1))
+		connection.SendString("queued")
+		if queued, err := connection.TrySend(Message{Data: []byte("overflowed")}); queued != test.queued || err != test.err {
+			t.Errorf("TrySend() with policy %d = %v, %v, want %v, %v", test.policy, queued, err, test.queued, test.err)
+		}
+		close(response.release)
+		connection.Close()
+	}
+}
+
+func TestDropOldestKeepsFlushes(t *testing.T) {
+	response := &flushCountingResponse{blockingResponse: newBlockingResponse()}
+	connection, err := Upgrade(response, httptest.NewRequest("GET", "/", nil),
+		WithManualFlush(), WithOverflowPolicy(OverflowDropOldest), WithQueueSize(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	connection.SendString("first")
+	<-response.blocked
+	results := newResults(t)
+	connection.Flush()
+	connection.SendAsync(Message{Data: []byte("dropped")}, results.callback("dropped"))
+	// the queue is full, so the oldest message is dropped rather than the flush ahead of it
+	connection.SendString("sent")
+	flushes := atomic.LoadInt32(&response.flushes)
+	close(response.release)
+	results.check(map[string]error{"dropped": ErrMessageDropped})
+	connection.Close()
+	// the requested flush, then the flush of the events sent before Close
+	if flushed := atomic.LoadInt32(&response.flushes) - flushes; flushed != 2 {
+		t.Errorf("flushed %d times, want 2", flushed)
+	}
+	if body := response.Body.String(); body != "data: first\n\ndata: sent\n\n" {
+		t.Errorf("body = %q", body)
+	}
+}
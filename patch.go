@@ -0,0 +1,161 @@
+package sse
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// PatchEvent is the event of the RFC 6902 JSON Patches sent by SendJSONPatch
+const PatchEvent = "patch"
+
+// PatchOperation is an operation of an RFC 6902 JSON Patch
+type PatchOperation struct {
+	Op    string
+	Path  string
+	Value interface{}
+}
+
+// MarshalJSON encodes the operation with a value unless it is a remove, so null values are
+// kept for add and replace
+func (operation PatchOperation) MarshalJSON() ([]byte, error) {
+	if operation.Op == "remove" {
+		return json.Marshal(struct {
+			Op   string `json:"op"`
+			Path string `json:"path"`
+		}{operation.Op, operation.Path})
+	}
+	return json.Marshal(struct {
+		Op    string      `json:"op"`
+		Path  string      `json:"path"`
+		Value interface{} `json:"value"`
+	}{operation.Op, operation.Path, operation.Value})
+}
+
+// SendJSONPatch sends the changes from the state last sent with SendJSONPatch to state as a
+// PatchEvent with an RFC 6902 JSON Patch, cutting bandwidth for large state updated often.
+// The first patch replaces the whole document, so clients apply patches to an empty document.
+// Nothing is sent when state is unchanged. A patch that isn't delivered, such as one dropped by
+// the connection's OverflowPolicy, throttling, pausing or coalescing, is followed by a patch
+// replacing the whole document again. Patches are flushed as they are written like SendAndWait's
+// messages, so their delivery is known. Redactions set by WithRedaction are applied to state
+// before it is diffed.
+func (connection *Connection) SendJSONPatch(state interface{}) error {
+	document, err := normalizeJSON(connection.options.marshalJSON, state)
+	if err != nil {
+		return err
+	}
+	for _, redaction := range connection.options.redactions {
+		document = redaction(document)
+	}
+	connection.patchMutex.Lock()
+	defer connection.patchMutex.Unlock()
+	// the client missed a patch, so the document it would apply the next diff to is unknown
+	if atomic.SwapInt32(&connection.patchDropped, 0) == 1 {
+		connection.patched = false
+	}
+	var operations []PatchOperation
+	if connection.patched {
+		operations = diffJSON(nil, "", connection.patchDocument, document)
+	} else {
+		operations = []PatchOperation{{Op: "replace", Path: "", Value: document}}
+	}
+	if len(operations) == 0 {
+		return nil
+	}
+	// the operations are sent as is, the document they were diffed from is already redacted
+	payload, err := connection.options.marshalJSON(operations)
+	if err != nil {
+		return err
+	}
+	message := Message{Event: PatchEvent, Data: payload}
+	// the writer may call back before Send returns, with patchMutex still held
+	message.onWritten = func(err error) {
+		if err != nil {
+			atomic.StoreInt32(&connection.patchDropped, 1)
+		}
+	}
+	if err := connection.send(&message); err != nil {
+		return err
+	}
+	connection.patchDocument = document
+	connection.patched = true
+	return nil
+}
+
+// normalizeJSON returns the generic json value of v marshaled with marshal, keeping numbers as json.Number so they
+// are compared exactly
+func normalizeJSON(marshal func(v interface{}) ([]byte, error), v interface{}) (interface{}, error) {
+	encoded, err := marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.UseNumber()
+	var value interface{}
+	err = decoder.Decode(&value)
+	return value, err
+}
+
+// diffJSON appends the operations patching from into to at path
+func diffJSON(operations []PatchOperation, path string, from interface{}, to interface{}) []PatchOperation {
+	switch to := to.(type) {
+	case map[string]interface{}:
+		from, ok := from.(map[string]interface{})
+		if !ok {
+			break
+		}
+		for _, key := range sortedKeys(from) {
+			if _, ok := to[key]; !ok {
+				operations = append(operations, PatchOperation{Op: "remove", Path: path + "/" + escapePointer(key)})
+			}
+		}
+		for _, key := range sortedKeys(to) {
+			if value, ok := from[key]; ok {
+				operations = diffJSON(operations, path+"/"+escapePointer(key), value, to[key])
+			} else {
+				operations = append(operations, PatchOperation{Op: "add", Path: path + "/" + escapePointer(key), Value: to[key]})
+			}
+		}
+		return operations
+	case []interface{}:
+		from, ok := from.([]interface{})
+		if !ok {
+			break
+		}
+		common := min(len(from), len(to))
+		for i := 0; i < common; i++ {
+			operations = diffJSON(operations, path+"/"+strconv.Itoa(i), from[i], to[i])
+		}
+		// removing from the end keeps the indices of the remaining elements stable
+		for i := len(from) - 1; i >= common; i-- {
+			operations = append(operations, PatchOperation{Op: "remove", Path: path + "/" + strconv.Itoa(i)})
+		}
+		for i := common; i < len(to); i++ {
+			operations = append(operations, PatchOperation{Op: "add", Path: path + "/" + strconv.Itoa(i), Value: to[i]})
+		}
+		return operations
+	}
+	if reflect.DeepEqual(from, to) {
+		return operations
+	}
+	return append(operations, PatchOperation{Op: "replace", Path: path, Value: to})
+}
+
+// escapePointer escapes a key for use as a JSON Pointer reference token
+func escapePointer(key string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(key, "~", "~0"), "/", "~1")
+}
+
+func sortedKeys(object map[string]interface{}) []string {
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
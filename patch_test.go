@@ -0,0 +1,57 @@
+package sse
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSendJSONPatchReplacesAfterDrop(t *testing.T) {
+	drop := true
+	dropFirst := func(next Sender) Sender {
+		return func(ctx context.Context, message Message) error {
+			if drop {
+				drop = false
+				return nil
+			}
+			return next(ctx, message)
+		}
+	}
+	recorder := httptest.NewRecorder()
+	connection, err := Upgrade(recorder, httptest.NewRequest("GET", "/", nil), WithMiddleware(dropFirst))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, state := range []map[string]int{{"a": 1}, {"a": 2}, {"a": 3}} {
+		if err := connection.SendJSONPatch(state); err != nil {
+			t.Fatal(err)
+		}
+	}
+	connection.Close()
+	want := "event: patch\ndata: [{\"op\":\"replace\",\"path\":\"\",\"value\":{\"a\":2}}]\n\n" +
+		"event: patch\ndata: [{\"op\":\"replace\",\"path\":\"/a\",\"value\":3}]\n\n"
+	if body := recorder.Body.String(); body != want {
+		t.Errorf("body = %q, want %q", body, want)
+	}
+}
+
+func TestSendJSONPatchReplacesAfterThrottledDrop(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	connection, err := Upgrade(recorder, httptest.NewRequest("GET", "/", nil), WithDebounce(PatchEvent, 20*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the second patch replaces the first while it waits out the debounce
+	connection.SendJSONPatch(map[string]int{"a": 1})
+	connection.SendJSONPatch(map[string]int{"a": 2, "b": 1})
+	time.Sleep(50 * time.Millisecond)
+	connection.SendJSONPatch(map[string]int{"a": 2, "b": 2})
+	time.Sleep(50 * time.Millisecond)
+	connection.Close()
+	body := recorder.Body.String()
+	if !strings.HasSuffix(body, "data: [{\"op\":\"replace\",\"path\":\"\",\"value\":{\"a\":2,\"b\":2}}]\n\n") {
+		t.Errorf("patch after a debounced drop didn't replace the document: %q", body)
+	}
+}
@@ -0,0 +1,47 @@
+package sse
+
+import "sync/atomic"
+
+// WithPauseBuffer holds up to size of the most recent events sent while the connection is
+// paused and delivers them on Resume. Without it, events sent while paused are dropped.
+func WithPauseBuffer(size int) Option {
+	return func(o *options) {
+		o.pauseBufferSize = size
+	}
+}
+
+// Pause stops delivering events to the client until Resume is called. Events sent while
+// paused are held by the pause buffer or dropped.
+func (connection *Connection) Pause() {
+	atomic.StoreInt32(&connection.paused, 1)
+}
+
+// Resume delivers events held while the connection was paused and continues streaming
+func (connection *Connection) Resume() {
+	if atomic.CompareAndSwapInt32(&connection.paused, 1, 0) {
+		select {
+		case connection.resumed <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// IsPaused returns whether delivery is paused on the connection
+func (connection *Connection) IsPaused() bool {
+	return atomic.LoadInt32(&connection.paused) == 1
+}
+
+// hold adds message to the held messages, dropping the oldest once size is reached
+func hold(held []Message, message Message, size int) []Message {
+	if size <= 0 {
+		message.written(ErrMessageDropped)
+		return held
+	}
+	if len(held) >= size {
+		for i := range held[:len(held)-size+1] {
+			held[i].written(ErrMessageDropped)
+		}
+		held = append(held[:0], held[len(held)-size+1:]...)
+	}
+	return append(held, message)
+}
@@ -0,0 +1,72 @@
+package sse
+
+// PayloadEncoder encodes event payloads sent with SendPayload, such as for protobuf json or
+// base64 encoded msgpack payloads
+type PayloadEncoder interface {
+	// Marshal encodes v as an event's data
+	Marshal(v interface{}) ([]byte, error)
+	// ContentType is the media type of the encoded payloads, for consumers negotiating the
+	// payload encoding
+	ContentType() string
+}
+
+// JSONEncoder is the PayloadEncoder encoding payloads as json, applying the connection's
+// redactions like SendJson
+var JSONEncoder PayloadEncoder = jsonEncoder{}
+
+type jsonEncoder struct{}
+
+func (jsonEncoder) Marshal(v interface{}) ([]byte, error) {
+	return JSONMarshal(v)
+}
+
+func (jsonEncoder) ContentType() string {
+	return "application/json"
+}
+
+// WithPayloadEncoder sets the PayloadEncoder of payloads sent with SendPayload, which is
+// JSONEncoder by default
+func WithPayloadEncoder(encoder PayloadEncoder) Option {
+	return func(o *options) {
+		o.payloadEncoder = encoder
+	}
+}
+
+// SendPayload encodes payload with the connection's PayloadEncoder and sends it for an event's
+// data without an id or event field
+func (connection *Connection) SendPayload(payload interface{}) error {
+	return connection.BuildMessage().SendPayload(payload)
+}
+
+// PayloadContentType returns the content type of payloads sent on the connection with
+// SendPayload
+func (connection *Connection) PayloadContentType() string {
+	if connection.options.payloadEncoder == nil {
+		return JSONEncoder.ContentType()
+	}
+	return connection.options.payloadEncoder.ContentType()
+}
+
+// WithEncoder sets the PayloadEncoder of this message's SendPayload, overriding the
+// connection's
+func (messageBuilder *MessageBuilder) WithEncoder(encoder PayloadEncoder) *MessageBuilder {
+	messageBuilder.encoder = encoder
+	return messageBuilder
+}
+
+// SendPayload encodes payload with the builder's or connection's PayloadEncoder and sends it
+// with the specified id and event attributes
+func (messageBuilder *MessageBuilder) SendPayload(payload interface{}) error {
+	encoder := messageBuilder.encoder
+	if encoder == nil {
+		encoder = messageBuilder.connection.options.payloadEncoder
+	}
+	if encoder == nil || encoder == JSONEncoder {
+		return messageBuilder.SendJson(payload)
+	}
+	data, err := encoder.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	return messageBuilder.SendBytes(data)
+}
@@ -0,0 +1,49 @@
+package sse
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// polyfillPadding is the comment prologue older EventSource polyfills need to receive before
+// they start delivering events
+var polyfillPadding = ":" + strings.Repeat(" ", 2048) + "\n"
+
+// WithPolyfillCompat enables the quirks older EventSource polyfills need: a 2KB comment
+// padding sent after the headers, a comment sent every commentInterval, and the last event
+// id read from a lastEventId query parameter when the Last-Event-ID header is absent.
+// Negotiate also streams to requests with a transport query parameter, which polyfills add
+// in place of an Accept header. A commentInterval of 0 disables the periodic comments, which
+// are sent as heartbeats and are superseded by WithHeartbeat.
+func WithPolyfillCompat(commentInterval time.Duration) Option {
+	return func(o *options) {
+		o.polyfill = true
+		o.padding = true
+		if commentInterval > 0 && o.heartbeatInterval == 0 {
+			o.heartbeatInterval = commentInterval
+		}
+	}
+}
+
+// WithPolyfillPadding sends the 2KB comment padding legacy EventSource polyfills need right
+// after the headers, without the other quirks enabled by WithPolyfillCompat
+func WithPolyfillPadding() Option {
+	return func(o *options) {
+		o.padding = true
+	}
+}
+
+// lastEventID returns the id of the last event the client received before reconnecting
+func (o *options) lastEventID(request *http.Request) string {
+	if id := request.Header.Get("Last-Event-ID"); len(id) > 0 || !o.polyfill && o.longPollTimeout == 0 {
+		return id
+	}
+	return request.URL.Query().Get("lastEventId")
+}
+
+// wantsStream returns whether a request negotiated by Negotiate should be upgraded
+func (o *options) wantsStream(request *http.Request) bool {
+	return acceptsEventStream(request) || o.wantsNDJSON(request) || o.wantsLongPoll(request) || o.wantsWebSocket(request) ||
+		(o.polyfill && len(request.URL.Query().Get("transport")) > 0)
+}
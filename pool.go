@@ -0,0 +1,36 @@
+package sse
+
+import "sync"
+
+// maxPooledData caps the capacity of data buffers returned to dataPool, so one large event
+// doesn't pin a large buffer for the life of the process
+const maxPooledData = 64 * 1024
+
+// dataPool holds the buffers string data is copied into for sending, reused once the
+// connection's writer has encoded the event
+var dataPool = sync.Pool{
+	New: func() interface{} {
+		data := make([]byte, 0, 256)
+		return &data
+	},
+}
+
+// setPooledData sets the message's data to a copy of data held in a pooled buffer
+func (message *Message) setPooledData(data string) {
+	buffer := dataPool.Get().(*[]byte)
+	*buffer = append((*buffer)[:0], data...)
+	message.Data = *buffer
+	message.pooled = buffer
+}
+
+// release returns the message's pooled data buffer for reuse. The message's data must not be
+// read afterwards.
+func (message *Message) release() {
+	if message.pooled == nil {
+		return
+	}
+	if cap(*message.pooled) <= maxPooledData {
+		dataPool.Put(message.pooled)
+	}
+	message.pooled = nil
+}
@@ -0,0 +1,68 @@
+package sse
+
+// Priority sets which of the connection's queues a message waits in
+type Priority int
+
+const (
+	// PriorityNormal queues a message behind those already sent
+	PriorityNormal Priority = iota
+	// PriorityHigh queues a message ahead of every normal priority message still waiting, such
+	// as for shutdown notices and auth expiry on a congested connection
+	PriorityHigh
+)
+
+// WithPriority sets the priority the message is queued with
+func (messageBuilder *MessageBuilder) WithPriority(priority Priority) *MessageBuilder {
+	messageBuilder.message.priority = priority
+	return messageBuilder
+}
+
+// SendPriority sends a message with priority. High priority messages are written before
+// normal priority messages waiting in the queue, in the order they were sent.
+func (connection *Connection) SendPriority(message Message, priority Priority) error {
+	message.priority = priority
+	return connection.send(&message)
+}
+
+// lane returns the queue message waits in for the connection's writer
+func (connection *Connection) lane(message *Message) chan Message {
+	if message.priority == PriorityHigh {
+		return connection.urgent
+	}
+	return connection.messages
+}
+
+// next receives a waiting message without blocking, taking high priority messages first
+func (w *streamWriter) next() (Message, bool) {
+	if message, ok := w.nextUrgent(); ok {
+		return message, true
+	}
+	select {
+	case message := <-w.urgent:
+		return message, true
+	case message := <-w.messages:
+		return message, true
+	default:
+		return Message{}, false
+	}
+}
+
+// nextUrgent receives a waiting high priority message without blocking
+func (w *streamWriter) nextUrgent() (Message, bool) {
+	select {
+	case message := <-w.urgent:
+		return message, true
+	default:
+		return Message{}, false
+	}
+}
+
+// drainUrgent enqueues the high priority messages already waiting, up to maxBatchSize
+func (w *streamWriter) drainUrgent() {
+	for w.batch.Len() < maxBatchSize {
+		message, ok := w.nextUrgent()
+		if !ok || !w.enqueue(&message) {
+			return
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package sse
+
+import (
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ReconnectReport summarizes a client's connection attempts and write errors over the
+// ReconnectMonitor's sliding window
+type ReconnectReport struct {
+	Key      string
+	Attempts int
+	Errors   int
+	Window   time.Duration
+}
+
+// ReconnectMonitor keeps count of upgrade attempts and write errors per client over a sliding
+// window so applications can plug in their own abuse detection
+type ReconnectMonitor struct {
+	// Key identifies the client making a request, defaulting to the remote IP address
+	Key func(request *http.Request) string
+
+	window    time.Duration
+	hook      func(report ReconnectReport) bool
+	mutex     sync.Mutex
+	clients   map[string]*clientActivity
+	lastSweep time.Time
+}
+
+type clientActivity struct {
+	attempts []time.Time
+	errors   []time.Time
+}
+
+// NewReconnectMonitor returns a ReconnectMonitor that calls hook with a client's report on
+// every upgrade attempt. The upgrade is refused with 429 Too Many Requests when hook
+// returns false.
+func NewReconnectMonitor(window time.Duration, hook func(report ReconnectReport) bool) *ReconnectMonitor {
+	return &ReconnectMonitor{
+		Key:     remoteIP,
+		window:  window,
+		hook:    hook,
+		clients: make(map[string]*clientActivity),
+	}
+}
+
+// Report returns the current report for a client's key
+func (monitor *ReconnectMonitor) Report(key string) ReconnectReport {
+	monitor.mutex.Lock()
+	defer monitor.mutex.Unlock()
+	return monitor.report(key, monitor.activity(key, time.Now()))
+}
+
+// WithReconnectMonitor records the upgrade attempt and the connection's write errors with
+// monitor, refusing the upgrade if the monitor's hook rejects the client
+func WithReconnectMonitor(monitor *ReconnectMonitor) Option {
+	return func(o *options) {
+		o.reconnectMonitor = monitor
+	}
+}
+
+// attempt records an upgrade attempt for key and returns whether the hook allows it
+func (monitor *ReconnectMonitor) attempt(key string) bool {
+	monitor.mutex.Lock()
+	now := time.Now()
+	activity := monitor.activity(key, now)
+	activity.attempts = append(activity.attempts, now)
+	report := monitor.report(key, activity)
+	monitor.mutex.Unlock()
+	return monitor.hook == nil || monitor.hook(report)
+}
+
+func (monitor *ReconnectMonitor) recordError(key string) {
+	monitor.mutex.Lock()
+	defer monitor.mutex.Unlock()
+	now := time.Now()
+	activity := monitor.activity(key, now)
+	activity.errors = append(activity.errors, now)
+}
+
+// activity returns the pruned activity of key, sweeping idle clients once per window
+func (monitor *ReconnectMonitor) activity(key string, now time.Time) *clientActivity {
+	cutoff := now.Add(-monitor.window)
+	if now.Sub(monitor.lastSweep) > monitor.window {
+		for k, activity := range monitor.clients {
+			if activity.prune(cutoff) {
+				delete(monitor.clients, k)
+			}
+		}
+		monitor.lastSweep = now
+	}
+	activity, ok := monitor.clients[key]
+	if !ok {
+		activity = &clientActivity{}
+		monitor.clients[key] = activity
+	}
+	activity.prune(cutoff)
+	return activity
+}
+
+func (monitor *ReconnectMonitor) report(key string, activity *clientActivity) ReconnectReport {
+	return ReconnectReport{
+		Key:      key,
+		Attempts: len(activity.attempts),
+		Errors:   len(activity.errors),
+		Window:   monitor.window,
+	}
+}
+
+// prune drops activity older than cutoff, returning whether no activity remains
+func (activity *clientActivity) prune(cutoff time.Time) bool {
+	activity.attempts = pruneTimes(activity.attempts, cutoff)
+	activity.errors = pruneTimes(activity.errors, cutoff)
+	return len(activity.attempts) == 0 && len(activity.errors) == 0
+}
+
+func pruneTimes(times []time.Time, cutoff time.Time) []time.Time {
+	i := 0
+	for i < len(times) && times[i].Before(cutoff) {
+		i++
+	}
+	return times[i:]
+}
+
+func remoteIP(request *http.Request) string {
+	host, _, err := net.SplitHostPort(request.RemoteAddr)
+	if err != nil {
+		return request.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,78 @@
+package sse
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"strings"
+)
+
+// Redaction transforms a decoded JSON payload before it is sent to the client
+type Redaction func(value interface{}) interface{}
+
+// DropFields removes the fields at each dot-separated path, such as "user.email", from JSON
+// payloads. Paths that cross an array are applied to every element of the array.
+func DropFields(paths ...string) Redaction {
+	return func(value interface{}) interface{} {
+		for _, path := range paths {
+			dropField(value, strings.Split(path, "."))
+		}
+		return value
+	}
+}
+
+// MaskPattern replaces matches of pattern in every string value of JSON payloads with mask
+func MaskPattern(pattern *regexp.Regexp, mask string) Redaction {
+	var redaction Redaction
+	redaction = func(value interface{}) interface{} {
+		switch v := value.(type) {
+		case string:
+			return pattern.ReplaceAllString(v, mask)
+		case map[string]interface{}:
+			for key, field := range v {
+				v[key] = redaction(field)
+			}
+		case []interface{}:
+			for i, element := range v {
+				v[i] = redaction(element)
+			}
+		}
+		return value
+	}
+	return redaction
+}
+
+// WithRedaction applies redactions to every payload sent with SendJson on the connection
+func WithRedaction(redactions ...Redaction) Option {
+	return func(o *options) {
+		o.redactions = append(o.redactions, redactions...)
+	}
+}
+
+func redact(data []byte, redactions []Redaction) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	for _, redaction := range redactions {
+		value = redaction(value)
+	}
+	return json.Marshal(value)
+}
+
+func dropField(value interface{}, path []string) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if len(path) == 1 {
+			delete(v, path[0])
+		} else if field, ok := v[path[0]]; ok {
+			dropField(field, path[1:])
+		}
+	case []interface{}:
+		for _, element := range v {
+			dropField(element, path)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package sse
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// WithRetryAfter sets the Retry-After header sent when an upgrade is refused with 429 Too Many
+// Requests, so well-behaved clients back off instead of retrying immediately
+func WithRetryAfter(retryAfter time.Duration) Option {
+	return func(o *options) {
+		o.retryAfter = retryAfter
+	}
+}
+
+// problem is an RFC 7807 problem details body describing why an upgrade was refused
+type problem struct {
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+	Detail string `json:"detail"`
+}
+
+// refuse responds to a refused upgrade with status and a problem details body describing err,
+// returning err for Upgrade
+func refuse(writer http.ResponseWriter, status int, err error, retryAfter time.Duration) error {
+	if status == http.StatusTooManyRequests && retryAfter > 0 {
+		seconds := (retryAfter + time.Second - 1) / time.Second
+		writer.Header().Set("Retry-After", strconv.FormatInt(int64(seconds), 10))
+	}
+	writer.Header().Set("Content-Type", "application/problem+json")
+	writer.WriteHeader(status)
+	json.NewEncoder(writer).Encode(problem{
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: err.Error(),
+	})
+	return err
+}
@@ -0,0 +1,97 @@
+package sse
+
+import (
+	"context"
+	"io"
+	"strings"
+	"time"
+)
+
+// ReplayTimestampPrefix starts a comment line recording the time the following event was
+// originally sent, such as ": t=2006-01-02T15:04:05.999999999Z07:00", for Replay to honor
+// the original timing of a capture
+const ReplayTimestampPrefix = "t="
+
+// ReplayOption configures Replay
+type ReplayOption func(*replayOptions)
+
+type replayOptions struct {
+	interval time.Duration
+	speed    float64
+}
+
+// ReplayInterval waits interval between each replayed event
+func ReplayInterval(interval time.Duration) ReplayOption {
+	return func(o *replayOptions) {
+		o.interval = interval
+	}
+}
+
+// ReplayTiming honors the original timing of a capture, read from timestamp comments
+// starting with ReplayTimestampPrefix, sped up by speed. A speed of 2 replays the capture
+// twice as fast. Events without a timestamp are sent without waiting.
+func ReplayTiming(speed float64) ReplayOption {
+	return func(o *replayOptions) {
+		o.speed = speed
+	}
+}
+
+// Replay decodes a previously captured stream from reader and sends its events on connection
+// until the capture ends or ctx is done
+func Replay(ctx context.Context, reader io.Reader, connection *Connection, opts ...ReplayOption) error {
+	options := &replayOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	decoder := NewDecoder(reader)
+	var previous, timestamp time.Time
+	if options.speed > 0 {
+		decoder.onComment = func(comment string) {
+			if strings.HasPrefix(comment, ReplayTimestampPrefix) {
+				if t, err := time.Parse(time.RFC3339Nano, strings.TrimPrefix(comment, ReplayTimestampPrefix)); err == nil {
+					timestamp = t
+				}
+			}
+		}
+	}
+	first := true
+	for event, err := range decoder.All() {
+		if err != nil {
+			return err
+		}
+		wait := options.interval
+		if first {
+			wait = 0
+		}
+		if options.speed > 0 && !timestamp.IsZero() {
+			if !previous.IsZero() {
+				wait = time.Duration(float64(timestamp.Sub(previous)) / options.speed)
+			}
+			previous = timestamp
+			timestamp = time.Time{}
+		}
+		first = false
+		if wait > 0 {
+			timer := time.NewTimer(wait)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return ctx.Err()
+			case <-timer.C:
+			}
+		} else if err := ctx.Err(); err != nil {
+			return err
+		}
+		builder := connection.BuildMessage()
+		if len(event.ID) > 0 {
+			builder.WithId(event.ID)
+		}
+		if len(event.Event) > 0 {
+			builder.WithEvent(event.Event)
+		}
+		if err := builder.SendString(event.Data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
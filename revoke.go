@@ -0,0 +1,56 @@
+package sse
+
+import "net/http"
+
+// AuthRevokedEvent is the event sent before a connection is closed because the principal it is
+// authorized as was revoked
+const AuthRevokedEvent = "auth-revoked"
+
+// PrincipalKey is the metadata key of the principal a connection is authorized as, such as the
+// user or session ID, which Broker.Revoke closes the connections of
+const PrincipalKey = "principal"
+
+// WithPrincipal sets the principal each connection is authorized as to what principal returns
+// for its request, such as the authenticated user, for Broker.Revoke to close the principal's
+// connections once it logs out or its credentials are revoked. Connections whose principal is
+// empty aren't revoked.
+func WithPrincipal(principal func(request *http.Request) string) Option {
+	return func(o *options) {
+		o.principal = principal
+	}
+}
+
+// principalOf returns the principal the request is authorized as by WithPrincipal
+func (o *options) principalOf(request *http.Request) string {
+	if o.principal == nil {
+		return ""
+	}
+	return o.principal(request)
+}
+
+// Revoke closes the connection with ErrAuthRevoked after sending an AuthRevokedEvent, without
+// delivering the messages still queued for it, once the principal it is authorized as can no
+// longer receive events. Like Close, Revoke returns once the connection has terminated and
+// must not be called from the connection's writer goroutine.
+func (connection *Connection) Revoke() {
+	connection.abort(ErrAuthRevoked)
+	<-connection.done
+}
+
+// Revoke revokes every connection subscribed to the broker as principal, set with WithPrincipal
+// or Connection.Set with PrincipalKey, returning how many were closed. Auth systems can call
+// Revoke on logout or token revocation so streams don't outlive the session they were opened
+// with.
+func (broker *Broker) Revoke(principal string) int {
+	var revoked []*Connection
+	for _, connection := range broker.Connections() {
+		if value, ok := connection.Get(PrincipalKey); ok && value == principal {
+			connection.abort(ErrAuthRevoked)
+			revoked = append(revoked, connection)
+		}
+	}
+	for _, connection := range revoked {
+		<-connection.done
+	}
+	return len(revoked)
+}
@@ -0,0 +1,82 @@
+package sse
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestBrokerRevoke(t *testing.T) {
+	broker := NewBroker(WithPrincipal(func(request *http.Request) string {
+		return request.URL.Query().Get("user")
+	}))
+	server := httptest.NewServer(broker)
+	defer server.Close()
+	decoders := make(map[string][]*Decoder)
+	for _, user := range []string{"alice", "alice", "bob"} {
+		response, err := http.Get(server.URL + "?user=" + user)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer response.Body.Close()
+		decoders[user] = append(decoders[user], NewDecoder(response.Body))
+	}
+	deadline := time.Now().Add(time.Second)
+	for broker.Len() < 3 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if revoked := broker.Revoke("alice"); revoked != 2 {
+		t.Errorf("Revoke(alice) = %d, want 2", revoked)
+	}
+	if revoked := broker.Revoke("carol"); revoked != 0 {
+		t.Errorf("Revoke(carol) = %d, want 0", revoked)
+	}
+	for _, decoder := range decoders["alice"] {
+		event, err := decoder.Decode()
+		if err != nil || event.Event != AuthRevokedEvent {
+			t.Errorf("Decode() = %+v, %v, want %s", event, err, AuthRevokedEvent)
+		}
+		if _, err := decoder.Decode(); err != io.EOF {
+			t.Errorf("Decode() after revoke = %v, want EOF", err)
+		}
+	}
+	deadline = time.Now().Add(time.Second)
+	for broker.Len() > 1 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if broker.Len() != 1 {
+		t.Fatalf("Len() = %d after revoking alice, want 1", broker.Len())
+	}
+	broker.Broadcast(Message{Event: "notice"})
+	if event, err := decoders["bob"][0].Decode(); err != nil || event.Event != "notice" {
+		t.Errorf("bob's Decode() = %+v, %v, want the notice", event, err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := broker.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestConnectionRevoke(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	connection, err := Upgrade(recorder, httptest.NewRequest("GET", "/", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	reasons := make(chan error, 1)
+	connection.OnClose(func(err error) { reasons <- err })
+	connection.Revoke()
+	if connection.IsOpen() {
+		t.Error("IsOpen() after Revoke")
+	}
+	if reason := <-reasons; reason != ErrAuthRevoked {
+		t.Errorf("closed with %v, want %v", reason, ErrAuthRevoked)
+	}
+	if body := recorder.Body.String(); body != "event: "+AuthRevokedEvent+"\ndata: \n\n" {
+		t.Errorf("body = %q", body)
+	}
+}
@@ -0,0 +1,94 @@
+package sse
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"strings"
+)
+
+// generatedRunes are the characters GenerateMessage builds fields from, weighted towards the
+// edge cases of the wire format: colons, leading spaces, multi-byte unicode and replacement
+// characters
+var generatedRunes = []rune("abcXYZ019 :;=-_{}\"\\\téß世界Δ�\U0001f600\U0001f4a9")
+
+// generatedLineBreaks are added to generated data, which may span multiple lines
+var generatedLineBreaks = []string{"\n", "\r\n", "\r", "\n\n"}
+
+// GenerateMessage returns a random Message the encoder accepts, favoring edge cases such as
+// empty fields, leading spaces and colons, multi-byte unicode and long lines
+func GenerateMessage(random *rand.Rand) Message {
+	return Message{
+		ID:    generateField(random, 64),
+		Event: generateField(random, 64),
+		Data:  []byte(generateData(random)),
+	}
+}
+
+// Generate implements testing/quick's Generator so Messages can be used in property tests
+func (Message) Generate(random *rand.Rand, size int) reflect.Value {
+	return reflect.ValueOf(GenerateMessage(random))
+}
+
+// RoundTrip encodes message to the wire format and decodes it with a Decoder, returning an
+// error if the decoded event differs from message. CRLF and CR line breaks in data are
+// decoded as LF.
+func RoundTrip(message Message) error {
+	var encoded bytes.Buffer
+	writeMessage(&encoded, &message)
+	event, err := NewDecoder(&encoded).Decode()
+	if err != nil {
+		return fmt.Errorf("decoding %q: %w", encoded.String(), err)
+	}
+	data := string(bytes.Join(splitLines(message.Data), []byte("\n")))
+	if event.ID != message.ID || event.Event != message.Event || event.Data != data {
+		return fmt.Errorf("encoded %q decoded as id %q event %q data %q", encoded.String(), event.ID, event.Event, event.Data)
+	}
+	return nil
+}
+
+// CheckRoundTrip runs RoundTrip on n generated messages, returning the first error
+func CheckRoundTrip(random *rand.Rand, n int) error {
+	for i := 0; i < n; i++ {
+		if err := RoundTrip(GenerateMessage(random)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// generateField returns an empty string a quarter of the time, otherwise a string of up to
+// maxLength runes with occasional long runs of a single rune
+func generateField(random *rand.Rand, maxLength int) string {
+	if random.Intn(4) == 0 {
+		return ""
+	}
+	var field strings.Builder
+	length := random.Intn(16) + 1
+	if random.Intn(8) == 0 {
+		length = random.Intn(maxLength) + 1
+	}
+	for field.Len() < length {
+		r := generatedRunes[random.Intn(len(generatedRunes))]
+		if random.Intn(16) == 0 {
+			field.WriteString(strings.Repeat(string(r), random.Intn(length)+1))
+		} else {
+			field.WriteRune(r)
+		}
+	}
+	return field.String()
+}
+
+// generateData returns a generated field that may be broken across lines, including with
+// leading and trailing line breaks
+func generateData(random *rand.Rand) string {
+	var data strings.Builder
+	for lines := random.Intn(4); lines >= 0; lines-- {
+		data.WriteString(generateField(random, 64*1024))
+		if lines > 0 || random.Intn(8) == 0 {
+			data.WriteString(generatedLineBreaks[random.Intn(len(generatedLineBreaks))])
+		}
+	}
+	return data.String()
+}
@@ -0,0 +1,22 @@
+package sse
+
+import (
+	"math/rand"
+	"testing"
+	"testing/quick"
+)
+
+func TestRoundTrip(t *testing.T) {
+	if err := quick.Check(func(message Message) bool {
+		if err := RoundTrip(message); err != nil {
+			t.Log(err)
+			return false
+		}
+		return true
+	}, &quick.Config{MaxCount: 2000}); err != nil {
+		t.Error(err)
+	}
+	if err := CheckRoundTrip(rand.New(rand.NewSource(1)), 2000); err != nil {
+		t.Error(err)
+	}
+}
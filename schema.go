@@ -0,0 +1,219 @@
+package sse
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// SchemaRegistry maps event names to the Validators of their payloads, such as JSON Schemas and
+// Go types, shared by every connection upgraded with WithSchemaRegistry
+type SchemaRegistry struct {
+	validators map[string]Validator
+	mutex      sync.RWMutex
+}
+
+// NewSchemaRegistry returns a SchemaRegistry without any schemas
+func NewSchemaRegistry() *SchemaRegistry {
+	return &SchemaRegistry{validators: make(map[string]Validator)}
+}
+
+// Register validates the data of events named event with validator
+func (registry *SchemaRegistry) Register(event string, validator Validator) {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+	registry.validators[event] = validator
+}
+
+// RegisterSchema validates the data of events named event against a JSON Schema. The type,
+// properties, required, additionalProperties, items and enum keywords are supported.
+func (registry *SchemaRegistry) RegisterSchema(event string, schema []byte) error {
+	validator, err := SchemaValidator(schema)
+	if err != nil {
+		return err
+	}
+	registry.Register(event, validator)
+	return nil
+}
+
+// RegisterType validates the data of events named event by decoding it into the type of
+// prototype, rejecting fields the type doesn't have
+func (registry *SchemaRegistry) RegisterType(event string, prototype interface{}) {
+	registry.Register(event, TypeValidator(prototype))
+}
+
+// Validate checks data against the schema registered for event, returning nil for events
+// without a schema
+func (registry *SchemaRegistry) Validate(event string, data []byte) error {
+	registry.mutex.RLock()
+	validator, ok := registry.validators[event]
+	registry.mutex.RUnlock()
+	if !ok {
+		return nil
+	}
+	return validator.Validate(data)
+}
+
+// WithSchemaRegistry validates events against the schemas of registry, as validators set with
+// WithValidator are, so WithValidationMode sets whether failures fail the send or are logged
+func WithSchemaRegistry(registry *SchemaRegistry) Option {
+	return func(o *options) {
+		registry.mutex.RLock()
+		defer registry.mutex.RUnlock()
+		for event, validator := range registry.validators {
+			WithValidator(event, validator)(o)
+		}
+	}
+}
+
+// TypeValidator returns a Validator decoding data into the type of prototype, rejecting fields
+// the type doesn't have
+func TypeValidator(prototype interface{}) Validator {
+	payloadType := reflect.TypeOf(prototype)
+	return ValidatorFunc(func(data []byte) error {
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		return decoder.Decode(reflect.New(payloadType).Interface())
+	})
+}
+
+// jsonSchema is the supported subset of a JSON Schema
+type jsonSchema struct {
+	Type                 schemaTypes            `json:"type"`
+	Properties           map[string]*jsonSchema `json:"properties"`
+	Required             []string               `json:"required"`
+	AdditionalProperties *bool                  `json:"additionalProperties"`
+	Items                *jsonSchema            `json:"items"`
+	Enum                 []interface{}          `json:"enum"`
+}
+
+// schemaTypes is a schema's type keyword, either one type or a list of types
+type schemaTypes []string
+
+func (types *schemaTypes) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*types = schemaTypes{single}
+		return nil
+	}
+	return json.Unmarshal(data, (*[]string)(types))
+}
+
+// SchemaValidator returns a Validator checking data against a JSON Schema. The type,
+// properties, required, additionalProperties, items and enum keywords are supported.
+func SchemaValidator(schema []byte) (Validator, error) {
+	var parsed jsonSchema
+	decoder := json.NewDecoder(bytes.NewReader(schema))
+	decoder.UseNumber()
+	if err := decoder.Decode(&parsed); err != nil {
+		return nil, fmt.Errorf("parsing json schema: %w", err)
+	}
+	return ValidatorFunc(func(data []byte) error {
+		value, err := normalizeJSON(json.Marshal, json.RawMessage(data))
+		if err != nil {
+			return err
+		}
+		return parsed.validate("", value)
+	}), nil
+}
+
+func (schema *jsonSchema) validate(path string, value interface{}) error {
+	if len(schema.Type) > 0 && !schema.Type.match(value) {
+		return fmt.Errorf("%s: expected %s", schemaPath(path), strings.Join(schema.Type, " or "))
+	}
+	if len(schema.Enum) > 0 && !enumContains(schema.Enum, value) {
+		return fmt.Errorf("%s: not one of the allowed values", schemaPath(path))
+	}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for _, required := range schema.Required {
+			if _, ok := v[required]; !ok {
+				return fmt.Errorf("%s: missing required property %q", schemaPath(path), required)
+			}
+		}
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			property, ok := schema.Properties[key]
+			if !ok {
+				if schema.AdditionalProperties != nil && !*schema.AdditionalProperties {
+					return fmt.Errorf("%s: unexpected property %q", schemaPath(path), key)
+				}
+				continue
+			}
+			if err := property.validate(path+"/"+escapePointer(key), v[key]); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		if schema.Items == nil {
+			return nil
+		}
+		for i, element := range v {
+			if err := schema.Items.validate(fmt.Sprintf("%s/%d", path, i), element); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// match returns whether value has one of the types
+func (types schemaTypes) match(value interface{}) bool {
+	for _, schemaType := range types {
+		switch v := value.(type) {
+		case nil:
+			if schemaType == "null" {
+				return true
+			}
+		case bool:
+			if schemaType == "boolean" {
+				return true
+			}
+		case string:
+			if schemaType == "string" {
+				return true
+			}
+		case json.Number:
+			if schemaType == "number" {
+				return true
+			}
+			if _, err := v.Int64(); err == nil && schemaType == "integer" {
+				return true
+			}
+		case []interface{}:
+			if schemaType == "array" {
+				return true
+			}
+		case map[string]interface{}:
+			if schemaType == "object" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func enumContains(enum []interface{}, value interface{}) bool {
+	for _, allowed := range enum {
+		if reflect.DeepEqual(allowed, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// schemaPath returns a JSON Pointer for error messages, naming the document's root
+func schemaPath(path string) string {
+	if len(path) == 0 {
+		return "payload"
+	}
+	return path
+}
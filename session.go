@@ -0,0 +1,88 @@
+package sse
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"sync"
+)
+
+// SessionEvent is the first event sent on a connection upgraded by Sessions, carrying the
+// session ID the client includes when POSTing messages to the ingest endpoint
+const SessionEvent = "session"
+
+// Sessions pairs each event stream with a session ID and an ingest endpoint, approximating
+// bidirectional messaging. Sessions is an http.Handler for the ingest endpoint, which accepts
+// POST requests with a session query parameter.
+type Sessions struct {
+	handler     func(connection *Connection, request *http.Request)
+	mutex       sync.Mutex
+	connections map[string]*Connection
+}
+
+// NewSessions returns Sessions that calls handler with the session's Connection for every
+// request POSTed to the ingest endpoint
+func NewSessions(handler func(connection *Connection, request *http.Request)) *Sessions {
+	return &Sessions{
+		handler:     handler,
+		connections: make(map[string]*Connection),
+	}
+}
+
+// Upgrade upgrades the request to an SSE connection bound to a new session and sends the
+// session ID to the client as a SessionEvent. The session ends when the connection closes.
+func (sessions *Sessions) Upgrade(writer http.ResponseWriter, request *http.Request, opts ...Option) (*Connection, string, error) {
+	sessionID, err := newSessionID()
+	if err != nil {
+		return nil, "", err
+	}
+	connection, err := Upgrade(writer, request, opts...)
+	if err != nil {
+		return nil, "", err
+	}
+	sessions.mutex.Lock()
+	sessions.connections[sessionID] = connection
+	sessions.mutex.Unlock()
+	connection.OnClose(func(error) {
+		sessions.mutex.Lock()
+		delete(sessions.connections, sessionID)
+		sessions.mutex.Unlock()
+	})
+	if err := connection.BuildMessage().WithEvent(SessionEvent).SendString(sessionID); err != nil {
+		connection.Close()
+		return nil, "", err
+	}
+	return connection, sessionID, nil
+}
+
+// Connection returns the Connection of a session and whether the session is active
+func (sessions *Sessions) Connection(sessionID string) (*Connection, bool) {
+	sessions.mutex.Lock()
+	defer sessions.mutex.Unlock()
+	connection, ok := sessions.connections[sessionID]
+	return connection, ok
+}
+
+// ServeHTTP delivers a POSTed request to the handler along with the Connection of the session
+// in the request's session query parameter
+func (sessions *Sessions) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodPost {
+		writer.Header().Set("Allow", http.MethodPost)
+		http.Error(writer, "messages must be POSTed", http.StatusMethodNotAllowed)
+		return
+	}
+	connection, ok := sessions.Connection(request.URL.Query().Get("session"))
+	if !ok || !connection.IsOpen() {
+		http.Error(writer, "unknown session", http.StatusNotFound)
+		return
+	}
+	sessions.handler(connection, request)
+}
+
+func newSessionID() (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(id), nil
+}
@@ -0,0 +1,62 @@
+package sse
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// SetHeartbeat changes the interval of the connection's heartbeats, set by WithHeartbeat, while
+// it is open, such as to lengthen keepalives behind a misbehaving proxy. An interval of 0
+// stops the heartbeats.
+func (connection *Connection) SetHeartbeat(interval time.Duration) {
+	atomic.StoreInt64(&connection.heartbeat, int64(interval))
+	select {
+	case connection.retuned <- struct{}{}:
+	default:
+	}
+}
+
+// SetWriteTimeout changes the deadline of the connection's writes, set by WithWriteTimeout,
+// from the next write. A timeout of 0 removes the deadline.
+func (connection *Connection) SetWriteTimeout(timeout time.Duration) {
+	atomic.StoreInt64(&connection.writeDeadline, int64(timeout))
+}
+
+// SetQueueLimit limits how many messages wait in each of the connection's queues to limit, up
+// to the queue size set by WithQueueSize, such as to shed the backlog of slow clients during
+// an incident. Messages sent to a queue over the limit are handled by the connection's
+// OverflowPolicy, as are those sent with TrySend or by a Broker. Senders waiting with
+// OverflowBlock only wait when the queue is full.
+func (connection *Connection) SetQueueLimit(limit int) {
+	atomic.StoreInt64(&connection.queueLimit, int64(limit))
+}
+
+// writeTimeout returns the deadline of the connection's writes
+func (connection *Connection) writeTimeout() time.Duration {
+	return time.Duration(atomic.LoadInt64(&connection.writeDeadline))
+}
+
+// limitsQueue returns whether lane holds as many messages as the connection's queue limit
+func (connection *Connection) limitsQueue(lane chan Message) bool {
+	limit := atomic.LoadInt64(&connection.queueLimit)
+	return limit < int64(cap(lane)) && int64(len(lane)) >= limit
+}
+
+// scheduleHeartbeat arms the heartbeat ticker with the connection's heartbeat interval, or
+// stops it without one
+func (w *streamWriter) scheduleHeartbeat() {
+	interval := time.Duration(atomic.LoadInt64(&w.connection.heartbeat))
+	if w.heartbeatTicker != nil {
+		w.heartbeatTicker.Stop()
+	}
+	if interval <= 0 {
+		w.heartbeatDue = nil
+		return
+	}
+	if w.heartbeatTicker == nil {
+		w.heartbeatTicker = time.NewTicker(interval)
+	} else {
+		w.heartbeatTicker.Reset(interval)
+	}
+	w.heartbeatDue = w.heartbeatTicker.C
+}
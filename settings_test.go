@@ -0,0 +1,64 @@
+package sse
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSetHeartbeat(t *testing.T) {
+	response := httptest.NewRecorder()
+	connection, err := Upgrade(response, httptest.NewRequest("GET", "/", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	connection.SetHeartbeat(5 * time.Millisecond)
+	time.Sleep(30 * time.Millisecond)
+	connection.SetHeartbeat(0)
+	// a heartbeat may already be waiting to be written when they stop
+	time.Sleep(10 * time.Millisecond)
+	connection.SendAndWait(context.Background(), Message{Data: []byte("marker")})
+	time.Sleep(30 * time.Millisecond)
+	connection.Close()
+	body := response.Body.String()
+	if !strings.Contains(body, ": \n") {
+		t.Errorf("no heartbeats written: %q", body)
+	}
+	if after := body[strings.Index(body, "data: marker"):]; strings.Contains(after, ": \n") {
+		t.Errorf("heartbeats written after they were stopped: %q", body)
+	}
+}
+
+func TestSetQueueLimit(t *testing.T) {
+	connection, response := upgradeBlocked(t, WithOverflowPolicy(OverflowDropNewest))
+	connection.SetQueueLimit(2)
+	results := newResults(t)
+	for _, name := range []string{"1", "2", "3"} {
+		connection.SendAsync(Message{Data: []byte(name)}, results.callback(name))
+	}
+	close(response.release)
+	results.check(map[string]error{"1": nil, "2": nil, "3": ErrMessageDropped})
+	connection.Close()
+}
+
+func TestBrokerSettings(t *testing.T) {
+	broker := NewBroker()
+	before := &Connection{}
+	if err := broker.subscribe(before, nil); err != nil {
+		t.Fatal(err)
+	}
+	broker.SetQueueLimit(4)
+	broker.SetWriteTimeout(time.Second)
+	broker.SetWriteTimeout(2 * time.Second)
+	after := &Connection{}
+	if err := broker.subscribe(after, nil); err != nil {
+		t.Fatal(err)
+	}
+	for _, connection := range []*Connection{before, after} {
+		if connection.queueLimit != 4 || connection.writeTimeout() != 2*time.Second {
+			t.Errorf("queue limit %d and write timeout %v, want 4 and 2s", connection.queueLimit, connection.writeTimeout())
+		}
+	}
+}
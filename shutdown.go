@@ -0,0 +1,53 @@
+package sse
+
+import (
+	"math/rand"
+	"time"
+)
+
+// shutdownReason is the reason of the SysReconnect event sent when a connection is closed with
+// a retry
+const shutdownReason = "shutdown"
+
+// WithStaggeredReconnect makes Broker.Shutdown ask each connection's client to reconnect after a
+// random delay of up to window, sent as a retry field before the connection is closed, so the
+// clients of an instance being replaced in a deploy reconnect spread across window instead of
+// all at once. A window under a millisecond leaves the clients' reconnection times unchanged.
+func WithStaggeredReconnect(window time.Duration) Option {
+	return func(o *options) {
+		o.reconnectWindow = window
+	}
+}
+
+// CloseWithRetry closes the connection like Close, sending a retry field of retry after the
+// messages already waiting so the client waits retry before reconnecting. With control events
+// a SysReconnect event is sent too.
+func (connection *Connection) CloseWithRetry(retry time.Duration) {
+	connection.closeAfter(retry)
+}
+
+// staggeredRetry returns a random retry of up to the window set by WithStaggeredReconnect, or 0
+// without one
+func (o *options) staggeredRetry() time.Duration {
+	window := int64(o.reconnectWindow / time.Millisecond)
+	if window <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(window)+1) * time.Millisecond
+}
+
+// shutDown delivers the queued messages of a connection being closed, then asks the client to
+// reconnect after retry unless retry is 0
+func (w *streamWriter) shutDown(retry time.Duration) error {
+	if err := w.deliverQueued(); err != nil || retry <= 0 {
+		return err
+	}
+	if !w.ndjson {
+		w.batch.WriteString(retryField(retry))
+	}
+	if w.options.controlEvents {
+		w.closeWith(w.options.reconnect(shutdownReason, retry))
+		return nil
+	}
+	return w.write()
+}
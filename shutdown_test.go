@@ -0,0 +1,54 @@
+package sse
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestShutdownStaggersReconnects(t *testing.T) {
+	window := time.Minute
+	broker := NewBroker(WithStaggeredReconnect(window))
+	server := httptest.NewServer(broker)
+	defer server.Close()
+	var decoders []*Decoder
+	for i := 0; i < 8; i++ {
+		response, err := http.Get(server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer response.Body.Close()
+		decoders = append(decoders, NewDecoder(response.Body))
+	}
+	deadline := time.Now().Add(time.Second)
+	for broker.Len() < len(decoders) && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	broker.Broadcast(Message{Event: "last", Data: []byte("queued")})
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := broker.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+	retries := make(map[time.Duration]struct{})
+	for _, decoder := range decoders {
+		event, err := decoder.Decode()
+		if err != nil || event.Event != "last" {
+			t.Fatalf("Decode() = %+v, %v, want the queued event", event, err)
+		}
+		if _, err := decoder.Decode(); err != io.EOF {
+			t.Fatalf("Decode() after shutdown = %v, want EOF", err)
+		}
+		retry := decoder.Retry()
+		if retry <= 0 || retry > window {
+			t.Errorf("Retry() = %v, want within %v", retry, window)
+		}
+		retries[retry] = struct{}{}
+	}
+	if len(retries) < 2 {
+		t.Errorf("every client was sent the same retry: %v", retries)
+	}
+}
@@ -0,0 +1,37 @@
+package sse
+
+import "net/http"
+
+// SnapshotEvent is the event carrying the initial state sent by WithSnapshot
+const SnapshotEvent = "snapshot"
+
+// WithSnapshot sends the initial state returned by provider as a json SnapshotEvent before any
+// live events. The snapshot is sent when the request has a snapshot=true query parameter or
+// no Last-Event-ID header, so clients resuming a stream aren't sent it again. The upgrade
+// fails with 500 Internal Server Error if provider returns an error.
+func WithSnapshot(provider func(request *http.Request) (interface{}, error)) Option {
+	return func(o *options) {
+		o.snapshot = provider
+	}
+}
+
+func (o *options) wantsSnapshot(request *http.Request) bool {
+	return request.URL.Query().Get("snapshot") == "true" || len(o.lastEventID(request)) == 0
+}
+
+func (o *options) snapshotMessage(request *http.Request) (*Message, error) {
+	state, err := o.snapshot(request)
+	if err != nil {
+		return nil, err
+	}
+	data, err := o.marshalJSON(state)
+	if err != nil {
+		return nil, err
+	}
+	if len(o.redactions) > 0 {
+		if data, err = redact(data, o.redactions); err != nil {
+			return nil, err
+		}
+	}
+	return &Message{Event: SnapshotEvent, Data: data}, nil
+}
@@ -3,28 +3,66 @@
 package sse
 
 import (
-	"encoding/json"
+	"bytes"
+	"context"
 	"errors"
-	"fmt"
-	"log"
+	"io"
 	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
+// errorBufferSize is how many write errors are held for Errors before they are logged instead
+const errorBufferSize = 16
+
+// connectionCount is the ID of the most recently upgraded connection
+var connectionCount uint64
+
 // Connection provides channels for sending event messages, closing the connection and
 // receiving errors from writing to the http response
 type Connection struct {
-	errors   <-chan error
-	messages chan<- Message
-	shutdown chan<- bool
-	isOpen   bool
+	bytesSent       int64
+	heartbeat       int64
+	writeDeadline   int64
+	queueLimit      int64
+	eventsSent      int64
+	bytesWritten    int64
+	messagesWritten int64
+	lastWrite       int64
+	id              uint64
+	paused          int32
+	openedAt        time.Time
+	errors          <-chan error
+	messages        chan Message
+	urgent          chan Message
+	shutdown        chan<- time.Duration
+	aborted         chan<- error
+	resumed         chan struct{}
+	retuned         chan struct{}
+	done            chan struct{}
+	closeMutex      sync.Mutex
+	closeReason     error
+	ctx             context.Context
+	cancel          context.CancelFunc
+	onClose         []func(reason error)
+	lastEventID     string
+	metadata        sync.Map
+	tags            sync.Map
+	patchMutex      sync.Mutex
+	patchDocument   interface{}
+	patched         bool
+	patchDropped    int32
+	sender          Sender
+	coalesceMutex   sync.Mutex
+	coalesced       map[string]*Message
+	options         *options
 }
 
 // BuildMessage returns a MessageBuilder, a fluent-style builder api for sending events
 func (connection *Connection) BuildMessage() *MessageBuilder {
-	return &MessageBuilder{
-		message:    &Message{},
-		connection: connection,
-	}
+	return &MessageBuilder{connection: connection}
 }
 
 // SendBytes sends a series of bytes for an event's data without an id or event field
@@ -43,129 +81,520 @@ func (connection *Connection) SendJson(data interface{}) error {
 	return connection.BuildMessage().SendJson(data)
 }
 
+// Send sends a message with its id, event and data attributes
+func (connection *Connection) Send(message Message) error {
+	return connection.send(&message)
+}
+
+// SendContext sends a message, returning ctx's error if ctx is done before the connection's
+// writer accepts the message, so producers never hang on a stuck client
+func (connection *Connection) SendContext(ctx context.Context, message Message) error {
+	return connection.sendContext(ctx, &message)
+}
+
+// SendStringContext sends a string for an event's data without an id or event field, giving up
+// when ctx is done
+func (connection *Connection) SendStringContext(ctx context.Context, data string) error {
+	message := Message{}
+	message.setPooledData(data)
+	return connection.sendContext(ctx, &message)
+}
+
+// SendComment sends a comment line, which clients ignore, such as to keep proxies and load
+// balancers from timing out an idle stream
+func (connection *Connection) SendComment(text string) error {
+	return connection.send(&Message{Data: []byte(text), isComment: true})
+}
+
+// LastEventID returns the id of the last event a reconnecting client received, sent in the
+// request's Last-Event-ID header, so handlers can resume the stream where the client left off.
+// LastEventID is empty for clients connecting for the first time.
+func (connection *Connection) LastEventID() string {
+	return connection.lastEventID
+}
+
+// Errors returns a channel receiving errors from writing to the http response, such as a
+// broken pipe, so applications can react to failed deliveries. Errors are logged instead
+// once the channel's buffer is full. The channel is closed when the client disconnects.
+func (connection *Connection) Errors() <-chan error {
+	return connection.errors
+}
+
+// ID returns the connection's ID, unique among the connections upgraded by this process, for
+// correlating logs and metrics with a stream
+func (connection *Connection) ID() uint64 {
+	return connection.id
+}
+
 // IsOpen returns whether connection is still open for sending event data
 func (connection *Connection) IsOpen() bool {
-	return connection.isOpen
+	select {
+	case <-connection.done:
+		return false
+	default:
+		return true
+	}
 }
 
-// Close sends a shutdown signal to close the connection for streaming data
+// Close sends a shutdown signal to close the connection for streaming data. Messages already
+// waiting to be sent, including those in the queue, are delivered, then Close returns once the connection has stopped
+// writing to the response so the handler can return. Close is safe to call more than once
+// and from multiple goroutines, but not from callbacks called by the connection's writer
+// goroutine, such as WithDeliveryCallback's, which would wait on themselves.
 func (connection *Connection) Close() {
-	connection.shutdown <- true
+	connection.closeAfter(0)
+}
+
+// closeAfter closes the connection like Close, asking the client to reconnect after retry
+// unless retry is 0
+func (connection *Connection) closeAfter(retry time.Duration) {
+	select {
+	case connection.shutdown <- retry:
+	case <-connection.done:
+	}
+	<-connection.done
+}
+
+// Context returns a context derived from the request's context, or the context passed to
+// UpgradeContext, that is cancelled once the connection terminates. Work done for the stream,
+// such as database calls and subscriptions, can use it to stop with the stream.
+func (connection *Connection) Context() context.Context {
+	return connection.ctx
+}
+
+// Done returns a channel that is closed when the connection has terminated, because the
+// client disconnected, Close was called or the server closed the stream. Nothing is written to
+// the response once Done is closed, so handlers can select on it before returning and
+// producer goroutines can stop sending.
+func (connection *Connection) Done() <-chan struct{} {
+	return connection.done
+}
+
+// OnClose registers onClose to be called once when the connection terminates, with the cause
+// of termination. The reason is nil when the connection was closed with Close. onClose is
+// called immediately if the connection has already terminated.
+func (connection *Connection) OnClose(onClose func(reason error)) {
+	connection.closeMutex.Lock()
+	select {
+	case <-connection.done:
+		connection.closeMutex.Unlock()
+		onClose(connection.closeReason)
+	default:
+		connection.onClose = append(connection.onClose, onClose)
+		connection.closeMutex.Unlock()
+	}
+}
+
+// terminate closes the done channel and calls the OnClose callbacks with reason, the first
+// time it is called
+func (connection *Connection) terminate(reason error) {
+	connection.closeMutex.Lock()
+	select {
+	case <-connection.done:
+		connection.closeMutex.Unlock()
+		return
+	default:
+	}
+	connection.closeReason = reason
+	close(connection.done)
+	connection.cancel()
+	onClose := connection.onClose
+	connection.onClose = nil
+	connection.closeMutex.Unlock()
+	for _, callback := range onClose {
+		callback(reason)
+	}
 }
 
 func (connection *Connection) send(message *Message) error {
-	if !connection.isOpen {
-		return errors.New("connection is closed")
+	return connection.sendContext(context.Background(), message)
+}
+
+// sendContext checks message and passes it through the connection's middleware to be queued.
+// Messages are validated as they were sent, before middleware transforms them.
+func (connection *Connection) sendContext(ctx context.Context, message *Message) error {
+	if err := connection.check(message); err != nil {
+		message.release()
+		return err
+	}
+	if connection.sender == nil || message.isComment {
+		return connection.queue(ctx, message)
+	}
+	// middleware may queue the message more than once or keep it, so its data is left to the
+	// garbage collector instead of being returned to the pool by the writer
+	message.pooled = nil
+	if message.onWritten != nil {
+		message.forwarding = &forwarding{}
 	}
-	connection.messages <- *message
-	return nil
+	err := connection.sender(ctx, *message)
+	if message.forwarding != nil && message.forwarding.settle() && err == nil {
+		message.written(ErrMessageDropped)
+	}
+	return err
+}
+
+// queue queues a checked message for the writer goroutine, giving up when ctx is done or the
+// connection closes. The writer takes ownership of a queued message's pooled data.
+func (connection *Connection) queue(ctx context.Context, message *Message) error {
+	if !connection.IsOpen() {
+		message.release()
+		return ErrConnectionClosed
+	}
+	if connection.options.coalescing && len(message.coalesceKey) > 0 && !message.isComment {
+		return connection.coalesce(ctx, message)
+	}
+	return connection.push(ctx, message)
+}
+
+// push queues a checked message by the connection's overflow policy, without waiting for room
+// in the queue when ctx is an offering context
+func (connection *Connection) push(ctx context.Context, message *Message) error {
+	offered, nonblocking := ctx.Value(offerKey{}).(*offering)
+	if nonblocking || connection.options.overflowPolicy != OverflowBlock {
+		queued, err := connection.offer(message)
+		if !queued {
+			message.release()
+			if err == nil {
+				message.written(ErrMessageDropped)
+			}
+		} else if nonblocking {
+			atomic.StoreInt32(&offered.queued, 1)
+		}
+		message.pooled = nil
+		return err
+	}
+	select {
+	case connection.lane(message) <- *message:
+		message.pooled = nil
+		return nil
+	case <-connection.done:
+		message.release()
+		return ErrConnectionClosed
+	case <-ctx.Done():
+		message.release()
+		return ctx.Err()
+	}
+}
+
+// check returns an error if message can't be sent on the connection
+func (connection *Connection) check(message *Message) error {
+	if !connection.IsOpen() {
+		return ErrConnectionClosed
+	}
+	if message.isComment {
+		return nil
+	}
+	if err := validateFields(message); err != nil {
+		return err
+	}
+	return connection.options.validate(message)
 }
 
 // MessageBuilder is a fluent-style builder api for sending events
 type MessageBuilder struct {
-	message    *Message
+	message    Message
 	connection *Connection
+	encoder    PayloadEncoder
 }
 
 // WithId adds an id attribute to event data
 func (messageBuilder *MessageBuilder) WithId(id string) *MessageBuilder {
-	messageBuilder.message.id = id
+	messageBuilder.message.ID = id
 	return messageBuilder
 }
 
 // WithEvent adds an event attribute to event data
 func (messageBuilder *MessageBuilder) WithEvent(event string) *MessageBuilder {
-	messageBuilder.message.event = event
+	messageBuilder.message.Event = event
 	return messageBuilder
 }
 
 // SendBytes sends a series of bytes with the specified id and event attributes
 func (messageBuilder *MessageBuilder) SendBytes(data []byte) error {
-	messageBuilder.message.data = data
-	return messageBuilder.connection.send(messageBuilder.message)
+	messageBuilder.message.Data = data
+	return messageBuilder.connection.send(&messageBuilder.message)
 }
 
 // SendBytes sends a string with the specified id and event attributes
 func (messageBuilder *MessageBuilder) SendString(data string) error {
-	messageBuilder.message.data = []byte(data)
-	return messageBuilder.connection.send(messageBuilder.message)
+	messageBuilder.message.setPooledData(data)
+	return messageBuilder.connection.send(&messageBuilder.message)
 }
 
 // SendJson marshals data into a json string and sends it without an id or event field
 func (messageBuilder *MessageBuilder) SendJson(data interface{}) error {
-	if data, err := json.Marshal(data); err != nil {
+	payload, err := messageBuilder.connection.options.marshalJSON(data)
+	if err != nil {
 		return err
-	} else {
-		messageBuilder.message.data = data
-		return messageBuilder.connection.send(messageBuilder.message)
 	}
+	if redactions := messageBuilder.connection.options.redactions; len(redactions) > 0 {
+		if payload, err = redact(payload, redactions); err != nil {
+			return err
+		}
+	}
+	messageBuilder.message.Data = payload
+	return messageBuilder.connection.send(&messageBuilder.message)
 }
 
-// Message contains id, event and data attributes of an event message
+// Message contains id, event and data attributes of an event message. ID and Event are
+// omitted from the event when empty.
 type Message struct {
-	id    string
-	event string
-	data  []byte
+	ID          string
+	Event       string
+	Data        []byte
+	isComment   bool
+	isFlush     bool
+	isCoalesced bool
+	coalesceKey string
+	priority    Priority
+	pooled      *[]byte
+	onWritten   func(err error)
+	forwarding  *forwarding
 }
 
 // Upgrade sends headers to client to upgrade the request to an SSE connection and
-// returns a Connection handle for sending messages.
-func Upgrade(writer http.ResponseWriter, request *http.Request) (*Connection, error) {
+// returns a Connection handle for sending messages. Options configure optional behavior
+// of the connection.
+func Upgrade(writer http.ResponseWriter, request *http.Request, opts ...Option) (*Connection, error) {
+	return UpgradeContext(request.Context(), writer, request, opts...)
+}
+
+// UpgradeContext upgrades the request like Upgrade, deriving the connection's Context from ctx
+// instead of the request's context. The connection terminates when ctx is done.
+func UpgradeContext(ctx context.Context, writer http.ResponseWriter, request *http.Request, opts ...Option) (*Connection, error) {
+	options := newOptions(opts)
+
+	// the lifetime event is written by the writer itself, so it isn't checked by Send
+	if options.lifetimeEvent != nil {
+		if err := validateFields(options.lifetimeEvent); err != nil {
+			writer.WriteHeader(http.StatusInternalServerError)
+			return nil, err
+		}
+	}
 
-	flusher, ok := writer.(http.Flusher)
-	if !ok {
-		return nil, errors.New("streaming not supported")
+	if options.cors != nil {
+		if err := options.cors.handle(writer, request); err == ErrOriginNotAllowed {
+			return nil, refuse(writer, http.StatusForbidden, err, 0)
+		} else if err != nil {
+			return nil, err
+		}
 	}
 
-	errorChannel := make(chan error)
-	messageChannel := make(chan Message)
-	shutdownChannel := make(chan bool)
+	if options.validateRequest {
+		if err := options.checkRequest(writer, request); err != nil {
+			return nil, refuse(writer, err.Status, err, 0)
+		}
+	}
+
+	if options.ipPolicy != nil && !options.ipPolicy.Allowed(request) {
+		return nil, refuse(writer, http.StatusForbidden, ErrSourceNotAllowed, 0)
+	}
+
+	var clientKey string
+	if options.reconnectMonitor != nil {
+		clientKey = options.reconnectMonitor.Key(request)
+		if !options.reconnectMonitor.attempt(clientKey) {
+			return nil, refuse(writer, http.StatusTooManyRequests, ErrTooManyReconnects, options.retryAfter)
+		}
+	}
+
+	var snapshot *Message
+	if options.snapshot != nil && options.wantsSnapshot(request) {
+		var err error
+		if snapshot, err = options.snapshotMessage(request); err != nil {
+			writer.WriteHeader(http.StatusInternalServerError)
+			return nil, err
+		}
+	}
+
+	var websocket *websocketResponse
+	if options.wantsWebSocket(request) {
+		var err error
+		if websocket, err = acceptWebSocket(writer, request, options.cors); err != nil {
+			return nil, err
+		}
+		writer = websocket
+	}
+
+	errorChannel := make(chan error, errorBufferSize)
+	messageChannel := make(chan Message, options.queueSize)
+	urgentChannel := make(chan Message, options.queueSize)
+	shutdownChannel := make(chan time.Duration)
+	abortChannel := make(chan error, 1)
+	resumed := make(chan struct{}, 1)
+	retuned := make(chan struct{}, 1)
+	connectionContext, cancel := context.WithCancel(ctx)
 	sseConnection := &Connection{
-		errors:   errorChannel,
-		messages: messageChannel,
-		shutdown: shutdownChannel,
-		isOpen:   true,
-	}
-
-	writer.Header().Set("Content-Type", "text/event-stream")
-	writer.Header().Set("Cache-Control", "no-cache")
-	writer.Header().Set("Connection", "keep-alive")
-	flusher.Flush()
-
-	handleError := func(err error) {
-		if err != nil {
-			select {
-			case errorChannel <- err:
-				break
-			default:
-				log.Println("sse write error: " + err.Error())
-			}
+		ctx:           connectionContext,
+		cancel:        cancel,
+		id:            atomic.AddUint64(&connectionCount, 1),
+		openedAt:      time.Now(),
+		errors:        errorChannel,
+		messages:      messageChannel,
+		urgent:        urgentChannel,
+		shutdown:      shutdownChannel,
+		aborted:       abortChannel,
+		resumed:       resumed,
+		retuned:       retuned,
+		done:          make(chan struct{}),
+		heartbeat:     int64(options.heartbeatInterval),
+		writeDeadline: int64(options.writeTimeout),
+		queueLimit:    int64(options.queueSize),
+		onClose:       options.onClose,
+		lastEventID:   options.lastEventID(request),
+		options:       options,
+	}
+	sseConnection.sender = sseConnection.chainMiddleware()
+	for key, value := range options.metadata {
+		sseConnection.Set(key, value)
+	}
+	if principal := options.principalOf(request); len(principal) > 0 {
+		sseConnection.Set(PrincipalKey, principal)
+	}
+	if options.tags != nil {
+		for _, tag := range options.tags(request) {
+			sseConnection.AddTag(tag)
 		}
 	}
+	if snapshot != nil {
+		snapshot = sseConnection.transform(*snapshot)
+	}
 
-	go func() {
-		for {
-			var err error
-			select {
-			case message := <-messageChannel:
-				if len(message.id) > 0 {
-					_, err = fmt.Fprintf(writer, "id: %s\n", message.id)
-					handleError(err)
-				}
-				if len(message.event) > 0 {
-					_, err = fmt.Fprintf(writer, "event: %s\n", message.event)
-					handleError(err)
-				}
-				_, err = fmt.Fprintf(writer, "data: %s\n\n", message.data)
-				handleError(err)
-				flusher.Flush()
-			case <-shutdownChannel:
-			case <-request.Context().Done():
-				sseConnection.isOpen = false
-				return
-			}
+	// ResponseController unwraps middleware that doesn't implement http.Flusher itself
+	controller := http.NewResponseController(writer)
+	ndjson := options.wantsNDJSON(request) || websocket != nil
+	if ndjson {
+		options.setHeaders(writer, request, ndjsonType)
+	} else {
+		options.setHeaders(writer, request, "text/event-stream")
+	}
+	if err := controller.Flush(); err != nil {
+		for header := range writer.Header() {
+			writer.Header().Del(header)
+		}
+		cancel()
+		if errors.Is(err, http.ErrNotSupported) {
+			return nil, ErrStreamingUnsupported
 		}
-	}()
+		return nil, err
+	}
+	if options.padding && !ndjson {
+		io.WriteString(writer, polyfillPadding)
+	}
+	if options.reconnectDelay > 0 && !ndjson {
+		io.WriteString(writer, retryField(options.reconnectDelay))
+	}
+	controller.Flush()
+	options.audit(AuditOpen, request, nil)
+
+	streamWriter := &streamWriter{
+		connection: sseConnection,
+		writer:     writer,
+		controller: controller,
+		request:    request,
+		options:    options,
+		clientKey:  clientKey,
+		snapshot:   snapshot,
+		ndjson:     ndjson,
+		longPoll:   options.wantsLongPoll(request),
+		generateID: options.eventIDs(sseConnection.lastEventID),
+		messages:   messageChannel,
+		urgent:     urgentChannel,
+		shutdown:   shutdownChannel,
+		aborted:    abortChannel,
+		resumed:    resumed,
+		retuned:    retuned,
+		errors:     errorChannel,
+	}
+	// writers that implement io.ReaderFrom (such as net/http's HTTP/1.1 response) receive
+	// each batch in one call, otherwise the batch is handed to Write. net/http's ReadFrom
+	// flushes what was written before it, so it isn't used when coalescing flushes.
+	if !options.coalescesFlushes() {
+		streamWriter.readerFrom, _ = writer.(io.ReaderFrom)
+	}
+	for _, onUpgrade := range options.onUpgrade {
+		onUpgrade(sseConnection)
+	}
+	go streamWriter.run()
+	if websocket != nil {
+		sseConnection.OnClose(func(error) { websocket.close() })
+		go func() { sseConnection.abort(websocket.read()) }()
+	}
 
 	return sseConnection, nil
 }
+
+// writeMessage encodes a message's id, event and data attributes onto a batch. The event is
+// encoded straight into the batch's spare capacity rather than formatted field by field.
+func writeMessage(batch *bytes.Buffer, message *Message) {
+	batch.Grow(len(message.ID) + len(message.Event) + len(message.Data) + frameOverhead)
+	batch.Write(appendMessage(batch.AvailableBuffer(), message))
+}
+
+// writeComment encodes text as comment lines onto a batch
+func writeComment(batch *bytes.Buffer, text []byte) {
+	batch.Grow(len(text) + frameOverhead)
+	batch.Write(appendLines(batch.AvailableBuffer(), ": ", text))
+}
+
+// frameOverhead is the size of the field names and line breaks of an event with single line
+// data, reserved when encoding an event so most events are encoded without growing the batch
+const frameOverhead = len("id: \nevent: \ndata: \n\n")
+
+// appendMessage appends the encoding of a message's id, event and data attributes to dst. Data
+// is written as one data field per line so multi-line payloads are received intact.
+func appendMessage(dst []byte, message *Message) []byte {
+	if len(message.ID) > 0 {
+		dst = append(dst, "id: "...)
+		dst = append(dst, message.ID...)
+		dst = append(dst, '\n')
+	}
+	if len(message.Event) > 0 {
+		dst = append(dst, "event: "...)
+		dst = append(dst, message.Event...)
+		dst = append(dst, '\n')
+	}
+	dst = appendLines(dst, "data: ", message.Data)
+	return append(dst, '\n')
+}
+
+// appendLines appends each line of text to dst as a field starting with prefix, splitting text
+// on CRLF, LF and CR line terminators
+func appendLines(dst []byte, prefix string, text []byte) []byte {
+	for {
+		i := bytes.IndexAny(text, "\r\n")
+		if i < 0 {
+			dst = append(dst, prefix...)
+			dst = append(dst, text...)
+			return append(dst, '\n')
+		}
+		dst = append(dst, prefix...)
+		dst = append(dst, text[:i]...)
+		dst = append(dst, '\n')
+		if text[i] == '\r' && i+1 < len(text) && text[i+1] == '\n' {
+			i++
+		}
+		text = text[i+1:]
+	}
+}
+
+// splitLines splits text on CRLF, LF and CR line terminators
+func splitLines(text []byte) [][]byte {
+	var lines [][]byte
+	for {
+		i := bytes.IndexAny(text, "\r\n")
+		if i < 0 {
+			return append(lines, text)
+		}
+		lines = append(lines, text[:i])
+		if text[i] == '\r' && i+1 < len(text) && text[i+1] == '\n' {
+			i++
+		}
+		text = text[i+1:]
+	}
+}
+
+// retryField encodes a retry field setting the client's reconnection time to delay
+func retryField(delay time.Duration) string {
+	return "retry: " + strconv.FormatInt(int64(delay/time.Millisecond), 10) + "\n\n"
+}
@@ -0,0 +1,132 @@
+package sse
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestAppendMessage(t *testing.T) {
+	tests := []struct {
+		name    string
+		message Message
+		want    string
+	}{
+		{"data only", Message{Data: []byte("hello")}, "data: hello\n\n"},
+		{"empty data", Message{}, "data: \n\n"},
+		{"id and event", Message{ID: "7", Event: "update", Data: []byte("x")}, "id: 7\nevent: update\ndata: x\n\n"},
+		{"LF", Message{Data: []byte("a\nb")}, "data: a\ndata: b\n\n"},
+		{"CRLF", Message{Data: []byte("a\r\nb")}, "data: a\ndata: b\n\n"},
+		{"CR", Message{Data: []byte("a\rb")}, "data: a\ndata: b\n\n"},
+		{"trailing LF", Message{Data: []byte("a\n")}, "data: a\ndata: \n\n"},
+		{"trailing CRLF", Message{Data: []byte("a\r\n")}, "data: a\ndata: \n\n"},
+		{"blank lines", Message{Data: []byte("a\n\nb")}, "data: a\ndata: \ndata: b\n\n"},
+		{"CR then LF lines", Message{Data: []byte("a\r\rb")}, "data: a\ndata: \ndata: b\n\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := string(appendMessage(nil, &test.message)); got != test.want {
+				t.Errorf("appendMessage() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestAppendLines(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"", ": \n"},
+		{"keepalive", ": keepalive\n"},
+		{"a\r\nb\rc\nd", ": a\n: b\n: c\n: d\n"},
+		{"a\n", ": a\n: \n"},
+	}
+	for _, test := range tests {
+		if got := string(appendLines(nil, ": ", []byte(test.text))); got != test.want {
+			t.Errorf("appendLines(%q) = %q, want %q", test.text, got, test.want)
+		}
+	}
+}
+
+func TestUpgradeStreamsMessages(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		connection, err := Upgrade(writer, request, WithQueueSize(16))
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		for i := 0; i < 100; i++ {
+			connection.BuildMessage().WithEvent("tick").SendString("line one\nline two")
+		}
+		connection.Close()
+	}))
+	defer server.Close()
+	response, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+	if contentType := response.Header.Get("Content-Type"); contentType != "text/event-stream" {
+		t.Errorf("Content-Type = %q", contentType)
+	}
+	decoder := NewDecoder(response.Body)
+	events := 0
+	for {
+		event, err := decoder.Decode()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		if event.Event != "tick" || event.Data != "line one\nline two" {
+			t.Fatalf("decoded %+v", event)
+		}
+		events++
+	}
+	if events != 100 {
+		t.Errorf("decoded %d events, want 100", events)
+	}
+}
+
+func TestConcurrentCloseSendAndIsOpen(t *testing.T) {
+	for i := 0; i < 20; i++ {
+		recorder := httptest.NewRecorder()
+		connection, err := Upgrade(recorder, httptest.NewRequest("GET", "/", nil), WithQueueSize(4))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var wg sync.WaitGroup
+		for j := 0; j < 4; j++ {
+			wg.Add(3)
+			go func() {
+				defer wg.Done()
+				connection.Close()
+			}()
+			go func() {
+				defer wg.Done()
+				for k := 0; k < 50; k++ {
+					if err := connection.SendString("event"); err != nil && err != ErrConnectionClosed {
+						t.Error(err)
+					}
+				}
+			}()
+			go func() {
+				defer wg.Done()
+				for k := 0; k < 50; k++ {
+					connection.IsOpen()
+				}
+			}()
+		}
+		wg.Wait()
+		connection.Close()
+		if connection.IsOpen() {
+			t.Error("IsOpen() after Close")
+		}
+		if err := connection.SendString("closed"); err != ErrConnectionClosed {
+			t.Errorf("SendString() after Close = %v, want %v", err, ErrConnectionClosed)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+// Package ssedebug provides a proxy that passes SSE streams between a client and server while
+// printing the decoded frames, the timing gaps between them, reconnects and malformed lines.
+package ssedebug
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/eighty4/sse"
+)
+
+// Proxy is an http.Handler forwarding requests to a target server and logging the SSE streams
+// of its responses
+type Proxy struct {
+	reverseProxy *httputil.ReverseProxy
+	output       io.Writer
+	outputMutex  sync.Mutex
+	connections  int64
+}
+
+// NewProxy returns a Proxy forwarding requests to target and writing its log to output
+func NewProxy(target *url.URL, output io.Writer) *Proxy {
+	proxy := &Proxy{output: output}
+	proxy.reverseProxy = httputil.NewSingleHostReverseProxy(target)
+	proxy.reverseProxy.FlushInterval = -1
+	proxy.reverseProxy.ModifyResponse = proxy.inspect
+	return proxy
+}
+
+// ServeHTTP forwards the request to the target server
+func (proxy *Proxy) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	proxy.reverseProxy.ServeHTTP(writer, request)
+}
+
+func (proxy *Proxy) inspect(response *http.Response) error {
+	request := response.Request
+	n := atomic.AddInt64(&proxy.connections, 1)
+	prefix := fmt.Sprintf("[%d] ", n)
+	connecting := fmt.Sprintf("%s %s from %s: %s", request.Method, request.URL.RequestURI(), request.RemoteAddr, response.Status)
+	if id := request.Header.Get("Last-Event-ID"); len(id) > 0 {
+		connecting += fmt.Sprintf(" (reconnect, Last-Event-ID %q)", id)
+	}
+	proxy.logf(prefix, "%s", connecting)
+	if !strings.HasPrefix(response.Header.Get("Content-Type"), "text/event-stream") {
+		return nil
+	}
+	reader, writer := io.Pipe()
+	response.Body = &teeBody{ReadCloser: response.Body, pipe: writer}
+	go proxy.decode(prefix, reader)
+	return nil
+}
+
+// decode logs the frames of a stream read through a teeBody
+func (proxy *Proxy) decode(prefix string, reader io.Reader) {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 4096), 1024*1024)
+	scanner.Split(sse.ScanLines)
+	opened := time.Now()
+	last := opened
+	var frame []string
+	var events int
+	for scanner.Scan() {
+		line := scanner.Text()
+		if len(line) > 0 {
+			if line[0] == ':' {
+				proxy.logf(prefix, "comment %q", line[1:])
+			} else if problem := malformed(line); len(problem) > 0 {
+				proxy.logf(prefix, "malformed line %q: %s", line, problem)
+			} else {
+				frame = append(frame, line)
+			}
+			continue
+		}
+		if len(frame) > 0 {
+			now := time.Now()
+			events++
+			proxy.logf(prefix, "+%s %s", now.Sub(last).Round(time.Millisecond), strings.Join(frame, " | "))
+			last = now
+			frame = frame[:0]
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		proxy.logf(prefix, "stream error: %s", err)
+	}
+	// the rest of a stream that couldn't be decoded is read so the teeBody doesn't block the
+	// proxied response
+	io.Copy(io.Discard, reader)
+	if len(frame) > 0 {
+		proxy.logf(prefix, "incomplete frame discarded: %s", strings.Join(frame, " | "))
+	}
+	proxy.logf(prefix, "closed after %s with %d events", time.Since(opened).Round(time.Millisecond), events)
+}
+
+func (proxy *Proxy) logf(prefix string, format string, args ...interface{}) {
+	proxy.outputMutex.Lock()
+	defer proxy.outputMutex.Unlock()
+	fmt.Fprintf(proxy.output, "%s %s"+format+"\n", append([]interface{}{time.Now().Format("15:04:05.000"), prefix}, args...)...)
+}
+
+// malformed describes what is wrong with a field line, or returns an empty string
+func malformed(line string) string {
+	field, value := line, ""
+	if i := strings.IndexByte(line, ':'); i >= 0 {
+		field, value = line[:i], strings.TrimPrefix(line[i+1:], " ")
+	}
+	switch field {
+	case "event", "data":
+		return ""
+	case "id":
+		if strings.ContainsRune(value, 0) {
+			return "id contains NUL and is ignored"
+		}
+		return ""
+	case "retry":
+		if _, err := strconv.ParseUint(value, 10, 63); err != nil {
+			return "retry is not a number of milliseconds and is ignored"
+		}
+		return ""
+	default:
+		return "unknown field is ignored"
+	}
+}
+
+// teeBody copies a response body to a pipe as it is read by the reverse proxy
+type teeBody struct {
+	io.ReadCloser
+	pipe *io.PipeWriter
+}
+
+func (body *teeBody) Read(p []byte) (int, error) {
+	n, err := body.ReadCloser.Read(p)
+	if n > 0 {
+		body.pipe.Write(p[:n])
+	}
+	if err != nil {
+		body.pipe.CloseWithError(err)
+	}
+	return n, err
+}
+
+func (body *teeBody) Close() error {
+	body.pipe.Close()
+	return body.ReadCloser.Close()
+}
@@ -0,0 +1,41 @@
+package sse
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Stats reports a connection's activity
+type Stats struct {
+	// Messages is how many events have been written to the response
+	Messages int64
+	// Bytes is how many bytes have been written to the response, including comments
+	Bytes int64
+	// LastWrite is when the response was last written to, or zero before the first write
+	LastWrite time.Time
+	// QueueDepth is how many sent messages are waiting to be written
+	QueueDepth int
+	// Uptime is how long the connection has been open
+	Uptime time.Duration
+}
+
+// Stats returns the connection's activity, such as for finding idle streams to close
+func (connection *Connection) Stats() Stats {
+	stats := Stats{
+		Messages:   atomic.LoadInt64(&connection.messagesWritten),
+		Bytes:      atomic.LoadInt64(&connection.bytesWritten),
+		QueueDepth: len(connection.messages) + len(connection.urgent),
+		Uptime:     time.Since(connection.openedAt),
+	}
+	if lastWrite := atomic.LoadInt64(&connection.lastWrite); lastWrite > 0 {
+		stats.LastWrite = time.Unix(0, lastWrite)
+	}
+	return stats
+}
+
+// recordWrite counts a write of size bytes delivering messages events
+func (connection *Connection) recordWrite(size int64, messages int) {
+	atomic.AddInt64(&connection.bytesWritten, size)
+	atomic.AddInt64(&connection.messagesWritten, int64(messages))
+	atomic.StoreInt64(&connection.lastWrite, time.Now().UnixNano())
+}
@@ -0,0 +1,67 @@
+package sse
+
+import "net/http"
+
+// WithTags tags each connection with the tags returned for its request, such as the user's
+// roles and tenant, for Broker.BroadcastAllOf and Broker.BroadcastAnyOf to target
+func WithTags(tags func(request *http.Request) []string) Option {
+	return func(o *options) {
+		o.tags = tags
+	}
+}
+
+// AddTag tags the connection with tag, grouping it with other connections of the tag
+// independently of the topics it's subscribed to
+func (connection *Connection) AddTag(tag string) {
+	connection.tags.Store(tag, struct{}{})
+}
+
+// RemoveTag removes tag from the connection
+func (connection *Connection) RemoveTag(tag string) {
+	connection.tags.Delete(tag)
+}
+
+// HasTag returns whether the connection is tagged with tag
+func (connection *Connection) HasTag(tag string) bool {
+	_, ok := connection.tags.Load(tag)
+	return ok
+}
+
+// BroadcastAllOf queues message for every connection subscribed to the broker that is tagged
+// with all of tags, such as every admin of a tenant, returning how many it was queued for.
+// Like Broadcast, BroadcastAllOf never waits for a slow client.
+func (broker *Broker) BroadcastAllOf(message Message, tags ...string) int {
+	return publish(broker.tagged(func(connection *Connection) bool {
+		for _, tag := range tags {
+			if !connection.HasTag(tag) {
+				return false
+			}
+		}
+		return true
+	}), message)
+}
+
+// BroadcastAnyOf queues message for every connection subscribed to the broker that is tagged
+// with any of tags, returning how many it was queued for. Like Broadcast, BroadcastAnyOf never
+// waits for a slow client.
+func (broker *Broker) BroadcastAnyOf(message Message, tags ...string) int {
+	return publish(broker.tagged(func(connection *Connection) bool {
+		for _, tag := range tags {
+			if connection.HasTag(tag) {
+				return true
+			}
+		}
+		return false
+	}), message)
+}
+
+// tagged returns the broker's connections whose tags match
+func (broker *Broker) tagged(match func(connection *Connection) bool) []*Connection {
+	var connections []*Connection
+	for _, connection := range broker.Connections() {
+		if match(connection) {
+			connections = append(connections, connection)
+		}
+	}
+	return connections
+}
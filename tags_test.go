@@ -0,0 +1,60 @@
+package sse
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBroadcastToTags(t *testing.T) {
+	broker := NewBroker()
+	tagged := map[string][]string{
+		"acme admin":  {"admin", "tenant:acme"},
+		"acme user":   {"tenant:acme"},
+		"globex beta": {"beta-user", "tenant:globex"},
+		"untagged":    nil,
+	}
+	opts := []Option{WithQueueSize(8), WithTags(func(request *http.Request) []string {
+		return strings.Fields(request.URL.Query().Get("tags"))
+	})}
+	for name, tags := range tagged {
+		request := httptest.NewRequest("GET", "/?tags="+strings.Join(tags, "+"), nil)
+		connection, err := Upgrade(httptest.NewRecorder(), request, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer connection.Close()
+		if err := broker.subscribe(connection, nil); err != nil {
+			t.Fatal(name, err)
+		}
+	}
+	tests := []struct {
+		name    string
+		publish func(message Message) int
+		want    int
+	}{
+		{"all of admin and tenant", func(message Message) int { return broker.BroadcastAllOf(message, "admin", "tenant:acme") }, 1},
+		{"all of tenant", func(message Message) int { return broker.BroadcastAllOf(message, "tenant:acme") }, 2},
+		{"all of disjoint", func(message Message) int { return broker.BroadcastAllOf(message, "admin", "beta-user") }, 0},
+		{"any of", func(message Message) int { return broker.BroadcastAnyOf(message, "admin", "beta-user") }, 2},
+		{"any of unknown", func(message Message) int { return broker.BroadcastAnyOf(message, "support") }, 0},
+	}
+	for _, test := range tests {
+		if published := test.publish(Message{Event: "announcement"}); published != test.want {
+			t.Errorf("%s published to %d connections, want %d", test.name, published, test.want)
+		}
+	}
+}
+
+func TestConnectionTags(t *testing.T) {
+	connection := &Connection{}
+	connection.AddTag("beta-user")
+	if !connection.HasTag("beta-user") || connection.HasTag("admin") {
+		t.Error("HasTag() doesn't report the added tag")
+	}
+	connection.RemoveTag("beta-user")
+	if connection.HasTag("beta-user") {
+		t.Error("HasTag() after RemoveTag")
+	}
+}
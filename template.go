@@ -0,0 +1,17 @@
+package sse
+
+import (
+	"bytes"
+	"html/template"
+)
+
+// SendTemplate renders tmpl with data as the event's data and sends it with the specified id
+// and event attributes, such as for streaming html fragments to hypermedia clients. Multi-line
+// output is sent as one data field per line, which clients rejoin with line breaks.
+func (messageBuilder *MessageBuilder) SendTemplate(tmpl *template.Template, data interface{}) error {
+	var rendered bytes.Buffer
+	if err := tmpl.Execute(&rendered, data); err != nil {
+		return err
+	}
+	return messageBuilder.SendBytes(rendered.Bytes())
+}
@@ -0,0 +1,129 @@
+package sse
+
+import "time"
+
+// rateLimit limits how often events with a name are written
+type rateLimit struct {
+	interval time.Duration
+	debounce bool
+}
+
+// limitedEvent is the writer's state for an event name with a rate limit
+type limitedEvent struct {
+	rateLimit
+	pending *Message
+	last    time.Time
+	due     time.Time
+}
+
+// WithThrottle writes at most one event named event per interval. An event sent before the
+// interval has passed waits until it has, replacing any event of the name already waiting, so
+// the client receives the latest value without the intermediate ones.
+func WithThrottle(event string, interval time.Duration) Option {
+	return func(o *options) {
+		o.limitRate(event, rateLimit{interval: interval})
+	}
+}
+
+// WithDebounce writes an event named event once no other event of the name has been sent for
+// quiet, dropping the events it replaced
+func WithDebounce(event string, quiet time.Duration) Option {
+	return func(o *options) {
+		o.limitRate(event, rateLimit{interval: quiet, debounce: true})
+	}
+}
+
+func (o *options) limitRate(event string, limit rateLimit) {
+	if o.rateLimits == nil {
+		o.rateLimits = make(map[string]rateLimit)
+	}
+	o.rateLimits[event] = limit
+}
+
+// limit returns whether message is held back by its event's rate limit, replacing the event
+// already held back
+func (w *streamWriter) limit(message *Message) bool {
+	limit, ok := w.options.rateLimits[message.Event]
+	if !ok || len(message.Event) == 0 {
+		return false
+	}
+	if w.limited == nil {
+		w.limited = make(map[string]*limitedEvent)
+	}
+	event := w.limited[message.Event]
+	if event == nil {
+		event = &limitedEvent{rateLimit: limit}
+		w.limited[message.Event] = event
+	}
+	now := time.Now()
+	if !event.debounce && event.pending == nil && now.Sub(event.last) >= event.interval {
+		event.last = now
+		return false
+	}
+	if event.pending != nil {
+		event.pending.release()
+		event.pending.written(ErrMessageDropped)
+	}
+	held := *message
+	event.pending = &held
+	if event.debounce {
+		event.due = now.Add(event.interval)
+	} else {
+		event.due = event.last.Add(event.interval)
+	}
+	w.scheduleLimited()
+	return true
+}
+
+// releaseLimited enqueues the held back events that are due, or all of them
+func (w *streamWriter) releaseLimited(all bool) {
+	now := time.Now()
+	for _, event := range w.limited {
+		if event.pending == nil || (!all && now.Before(event.due)) {
+			continue
+		}
+		message := event.pending
+		event.pending = nil
+		event.last = now
+		if w.connection.IsPaused() {
+			w.held = hold(w.held, *message, w.options.pauseBufferSize)
+		} else {
+			w.encode(message)
+		}
+	}
+	w.scheduleLimited()
+}
+
+// scheduleLimited arms the rate limit timer for the next held back event to be due
+func (w *streamWriter) scheduleLimited() {
+	var next time.Time
+	for _, event := range w.limited {
+		if event.pending != nil && (next.IsZero() || event.due.Before(next)) {
+			next = event.due
+		}
+	}
+	if w.limitTimer != nil {
+		w.limitTimer.Stop()
+	}
+	if next.IsZero() {
+		w.limitDue = nil
+		return
+	}
+	if w.limitTimer == nil {
+		w.limitTimer = time.NewTimer(time.Until(next))
+	} else {
+		w.limitTimer.Reset(time.Until(next))
+	}
+	w.limitDue = w.limitTimer.C
+}
+
+// abandonLimited reports the held back events to their senders as unwritten
+func (w *streamWriter) abandonLimited() {
+	for _, event := range w.limited {
+		if event.pending != nil {
+			event.pending.release()
+			event.pending.written(ErrConnectionClosed)
+			event.pending = nil
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package sse
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRateLimitsReportEachMessageOnce(t *testing.T) {
+	tests := []struct {
+		name  string
+		limit Option
+		want  map[string]error
+		body  string
+	}{
+		{
+			"throttle",
+			WithThrottle("tick", 30*time.Millisecond),
+			map[string]error{"tick 1": nil, "tick 2": ErrMessageDropped, "tick 3": nil},
+			"event: tick\ndata: 1\n\nevent: tick\ndata: 3\n\n",
+		},
+		{
+			"debounce",
+			WithDebounce("tick", 30*time.Millisecond),
+			map[string]error{"tick 1": ErrMessageDropped, "tick 2": ErrMessageDropped, "tick 3": nil},
+			"event: tick\ndata: 3\n\n",
+		},
+	}
+	for _, test := range tests {
+		for name, opts := range map[string][]Option{
+			"queue":      {test.limit, WithQueueSize(8)},
+			"middleware": {test.limit, WithQueueSize(8), WithMiddleware(passThrough)},
+		} {
+			t.Run(test.name+" "+name, func(t *testing.T) {
+				recorder := httptest.NewRecorder()
+				connection, err := Upgrade(recorder, httptest.NewRequest("GET", "/", nil), opts...)
+				if err != nil {
+					t.Fatal(err)
+				}
+				results := newResults(t)
+				for _, value := range []string{"1", "2", "3"} {
+					message := Message{Event: "tick", Data: []byte(value)}
+					connection.SendAsync(message, results.callback("tick "+value))
+				}
+				results.check(test.want)
+				connection.Close()
+				if body := recorder.Body.String(); body != test.body {
+					t.Errorf("body = %q, want %q", body, test.body)
+				}
+			})
+		}
+	}
+}
+
+func TestRateLimitsReportHeldMessagesOnClose(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	connection, err := Upgrade(recorder, httptest.NewRequest("GET", "/", nil), WithDebounce("tick", time.Hour), WithQueueSize(8))
+	if err != nil {
+		t.Fatal(err)
+	}
+	results := newResults(t)
+	connection.SendAsync(Message{Event: "tick", Data: []byte("1")}, results.callback("closed"))
+	// a held back event is delivered when the connection closes gracefully
+	connection.Close()
+	results.check(map[string]error{"closed": nil})
+	if body := recorder.Body.String(); !strings.Contains(body, "data: 1") {
+		t.Errorf("body = %q", body)
+	}
+
+	connection, response := upgradeBlocked(t, WithDebounce("tick", time.Hour))
+	results = newResults(t)
+	connection.SendAsync(Message{Event: "tick", Data: []byte("1")}, results.callback("aborted"))
+	connection.abort(ErrSlowClient)
+	close(response.release)
+	results.check(map[string]error{"aborted": ErrConnectionClosed})
+}
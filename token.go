@@ -0,0 +1,61 @@
+package sse
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// IssueStreamToken returns a short-lived token for subject signed with secret. EventSource
+// can't set request headers, so the token is meant to be passed as a query parameter in
+// place of a long-lived credential.
+func IssueStreamToken(secret []byte, subject string, ttl time.Duration) string {
+	payload := base64.RawURLEncoding.EncodeToString([]byte(subject)) + "." +
+		strconv.FormatInt(time.Now().Add(ttl).Unix(), 10)
+	return payload + "." + signStreamToken(secret, payload)
+}
+
+// VerifyStreamToken checks token's signature and expiry and returns the subject it was
+// issued for
+func VerifyStreamToken(secret []byte, token string) (string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", ErrInvalidStreamToken
+	}
+	payload := parts[0] + "." + parts[1]
+	if !hmac.Equal([]byte(parts[2]), []byte(signStreamToken(secret, payload))) {
+		return "", ErrInvalidStreamToken
+	}
+	expiry, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return "", ErrInvalidStreamToken
+	}
+	if time.Now().Unix() >= expiry {
+		return "", ErrStreamTokenExpired
+	}
+	subject, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return "", ErrInvalidStreamToken
+	}
+	return string(subject), nil
+}
+
+// VerifyStreamTokenParam verifies the token carried in the request's query parameter param
+// and returns the subject it was issued for
+func VerifyStreamTokenParam(request *http.Request, param string, secret []byte) (string, error) {
+	token := request.URL.Query().Get(param)
+	if len(token) == 0 {
+		return "", ErrMissingStreamToken
+	}
+	return VerifyStreamToken(secret, token)
+}
+
+func signStreamToken(secret []byte, payload string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
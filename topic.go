@@ -0,0 +1,95 @@
+package sse
+
+import "strings"
+
+// TopicSeparator separates the levels of a topic, such as orders.eu.created, unless a
+// Broker's topic hierarchy sets another with WithTopicHierarchy
+const TopicSeparator = "."
+
+const (
+	// singleLevelWildcard matches exactly one level of a topic
+	singleLevelWildcard = "*"
+	// multiLevelWildcard matches the rest of a topic's levels, including none
+	multiLevelWildcard = "#"
+)
+
+// TopicPropagation sets which subscribers of related topics in a Broker's topic hierarchy
+// receive a message published to a topic. Policies can be combined with |.
+type TopicPropagation int
+
+const (
+	// PropagateToAncestors publishes a topic's messages to the subscribers of every topic above
+	// it, so a tenant's dashboard subscribed to acme receives acme/eu/api's messages
+	PropagateToAncestors TopicPropagation = 1 << iota
+	// PropagateToDescendants publishes a topic's messages to the subscribers of every topic
+	// below it, so a notice published to acme reaches acme/eu/api's subscribers
+	PropagateToDescendants
+)
+
+// WithTopicHierarchy models a Broker's topics as a hierarchy with levels separated by separator,
+// such as tenant/region/service, propagating messages to the subscribers of related topics by
+// propagation. Wildcard subscriptions match levels by separator too.
+func WithTopicHierarchy(separator string, propagation TopicPropagation) Option {
+	return func(o *options) {
+		o.topicSeparator = separator
+		o.topicPropagation = propagation
+	}
+}
+
+// MatchTopic returns whether topic matches pattern, where levels of the pattern separated by
+// TopicSeparator are matched MQTT-style: * matches exactly one level and #, as the last level,
+// matches any number of levels including none. orders.* matches orders.created but not
+// orders.eu.created, which orders.# matches along with orders itself.
+func MatchTopic(pattern string, topic string) bool {
+	return matchTopic(pattern, topic, TopicSeparator, false)
+}
+
+// matchTopic returns whether topic matches pattern with levels separated by separator, or with
+// descendants, whether a topic below topic could
+func matchTopic(pattern string, topic string, separator string, descendants bool) bool {
+	if !isTopicPattern(pattern, separator) && !descendants {
+		return pattern == topic
+	}
+	patternLevels := strings.Split(pattern, separator)
+	topicLevels := strings.Split(topic, separator)
+	for i, level := range patternLevels {
+		if level == multiLevelWildcard && i == len(patternLevels)-1 {
+			return true
+		}
+		if i == len(topicLevels) {
+			return descendants
+		}
+		if level != singleLevelWildcard && level != topicLevels[i] {
+			return false
+		}
+	}
+	return len(patternLevels) == len(topicLevels)
+}
+
+// isTopicPattern returns whether topic has wildcard levels
+func isTopicPattern(topic string, separator string) bool {
+	for _, level := range strings.Split(topic, separator) {
+		if level == singleLevelWildcard || level == multiLevelWildcard {
+			return true
+		}
+	}
+	return false
+}
+
+// topicAncestors returns the topics above topic in its hierarchy, nearest first
+func topicAncestors(topic string, separator string) []string {
+	var ancestors []string
+	for i := strings.LastIndex(topic, separator); i > 0; i = strings.LastIndex(topic, separator) {
+		topic = topic[:i]
+		ancestors = append(ancestors, topic)
+	}
+	return ancestors
+}
+
+// separator returns the separator of a Broker's topic levels
+func (o *options) separator() string {
+	if len(o.topicSeparator) == 0 {
+		return TopicSeparator
+	}
+	return o.topicSeparator
+}
@@ -0,0 +1,98 @@
+package sse
+
+import "testing"
+
+func TestMatchTopic(t *testing.T) {
+	tests := []struct {
+		pattern string
+		topic   string
+		want    bool
+	}{
+		{"orders", "orders", true},
+		{"orders", "orders.created", false},
+		{"orders.*", "orders.created", true},
+		{"orders.*", "orders", false},
+		{"orders.*", "orders.eu.created", false},
+		{"*.created", "orders.created", true},
+		{"*.created", "orders.eu.created", false},
+		{"orders.*.created", "orders.eu.created", true},
+		{"orders.*.created", "orders.eu.updated", false},
+		{"orders.#", "orders", true},
+		{"orders.#", "orders.created", true},
+		{"orders.#", "orders.eu.created", true},
+		{"orders.#", "payments.created", false},
+		{"#", "orders.eu.created", true},
+		{"orders.#.created", "orders.eu.created", false},
+		{"*", "orders", true},
+		{"*", "orders.created", false},
+	}
+	for _, test := range tests {
+		if got := MatchTopic(test.pattern, test.topic); got != test.want {
+			t.Errorf("MatchTopic(%q, %q) = %v, want %v", test.pattern, test.topic, got, test.want)
+		}
+	}
+}
+
+func TestIsTopicPattern(t *testing.T) {
+	tests := []struct {
+		topic     string
+		separator string
+		want      bool
+	}{
+		{"orders.created", ".", false},
+		{"orders.*", ".", true},
+		{"orders.#", ".", true},
+		{"orders*", ".", false},
+		{"acme/*", "/", true},
+		{"acme.*", "/", false},
+	}
+	for _, test := range tests {
+		if got := isTopicPattern(test.topic, test.separator); got != test.want {
+			t.Errorf("isTopicPattern(%q, %q) = %v, want %v", test.topic, test.separator, got, test.want)
+		}
+	}
+}
+
+func TestMatchTopicDescendants(t *testing.T) {
+	tests := []struct {
+		pattern string
+		topic   string
+		want    bool
+	}{
+		{"acme/eu/api", "acme", true},
+		{"acme/eu/api", "acme/eu", true},
+		{"acme/eu/api", "acme/us", false},
+		{"acme/*/api", "acme", true},
+		{"*/eu", "acme", true},
+		{"acme", "acme/eu", false},
+		{"other/eu", "acme", false},
+	}
+	for _, test := range tests {
+		if got := matchTopic(test.pattern, test.topic, "/", true); got != test.want {
+			t.Errorf("matchTopic(%q, %q) with descendants = %v, want %v", test.pattern, test.topic, got, test.want)
+		}
+	}
+}
+
+func TestTopicAncestors(t *testing.T) {
+	tests := []struct {
+		topic string
+		want  []string
+	}{
+		{"acme/eu/api", []string{"acme/eu", "acme"}},
+		{"acme", nil},
+		{"/acme", nil},
+	}
+	for _, test := range tests {
+		got := topicAncestors(test.topic, "/")
+		if len(got) != len(test.want) {
+			t.Errorf("topicAncestors(%q) = %q, want %q", test.topic, got, test.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != test.want[i] {
+				t.Errorf("topicAncestors(%q) = %q, want %q", test.topic, got, test.want)
+			}
+		}
+	}
+}
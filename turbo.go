@@ -0,0 +1,35 @@
+package sse
+
+import (
+	"html"
+	"strings"
+)
+
+// Turbo Stream actions supported by Hotwire Turbo
+const (
+	TurboAppend  = "append"
+	TurboPrepend = "prepend"
+	TurboReplace = "replace"
+	TurboUpdate  = "update"
+	TurboRemove  = "remove"
+	TurboBefore  = "before"
+	TurboAfter   = "after"
+	TurboRefresh = "refresh"
+)
+
+// SendTurboStream sends a turbo-stream element applying action to the element with the id
+// target, for pages connected with <turbo-stream-source>. The fragment is wrapped in the
+// element's template and is ignored by actions that don't take content, such as TurboRemove.
+func (connection *Connection) SendTurboStream(action string, target string, fragment string) error {
+	var element strings.Builder
+	element.WriteString(`<turbo-stream action="` + html.EscapeString(action) + `"`)
+	if len(target) > 0 {
+		element.WriteString(` target="` + html.EscapeString(target) + `"`)
+	}
+	element.WriteString(">")
+	if action != TurboRemove && action != TurboRefresh {
+		element.WriteString("<template>" + fragment + "</template>")
+	}
+	element.WriteString("</turbo-stream>")
+	return connection.SendString(element.String())
+}
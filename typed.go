@@ -0,0 +1,27 @@
+package sse
+
+// Typed wraps a Connection whose events all carry payloads of type T, so the compiler checks
+// what a stream sends. Payloads are encoded with the connection's PayloadEncoder.
+type Typed[T any] struct {
+	connection *Connection
+}
+
+// NewTyped returns a Typed sending payloads of type T on connection
+func NewTyped[T any](connection *Connection) *Typed[T] {
+	return &Typed[T]{connection: connection}
+}
+
+// Send sends payload as the data of an event, without an event field when event is empty
+func (typed *Typed[T]) Send(event string, payload T) error {
+	return typed.connection.BuildMessage().WithEvent(event).SendPayload(payload)
+}
+
+// SendWithID sends payload as the data of an event with an id
+func (typed *Typed[T]) SendWithID(id string, event string, payload T) error {
+	return typed.connection.BuildMessage().WithId(id).WithEvent(event).SendPayload(payload)
+}
+
+// Connection returns the wrapped Connection
+func (typed *Typed[T]) Connection() *Connection {
+	return typed.connection
+}
@@ -0,0 +1,67 @@
+package sse
+
+import (
+	"fmt"
+)
+
+// Validator checks an event's data before it is sent to the client
+type Validator interface {
+	Validate(data []byte) error
+}
+
+// ValidatorFunc adapts a func to a Validator
+type ValidatorFunc func(data []byte) error
+
+// Validate calls f(data)
+func (f ValidatorFunc) Validate(data []byte) error {
+	return f(data)
+}
+
+// ValidationMode sets how payloads failing a Validator are handled
+type ValidationMode int
+
+const (
+	// ValidateStrict fails the send with the validation error
+	ValidateStrict ValidationMode = iota
+	// ValidateWarn logs the validation error and sends the event anyway
+	ValidateWarn
+)
+
+// WithValidator checks the data of events named event with validator before they are sent.
+// Events without an event field are validated by a validator registered for "message".
+func WithValidator(event string, validator Validator) Option {
+	return func(o *options) {
+		if o.validators == nil {
+			o.validators = make(map[string]Validator)
+		}
+		o.validators[event] = validator
+	}
+}
+
+// WithValidationMode sets how events failing validation are handled, defaulting to
+// ValidateStrict
+func WithValidationMode(mode ValidationMode) Option {
+	return func(o *options) {
+		o.validationMode = mode
+	}
+}
+
+func (o *options) validate(message *Message) error {
+	event := message.Event
+	if len(event) == 0 {
+		event = "message"
+	}
+	validator, ok := o.validators[event]
+	if !ok {
+		return nil
+	}
+	if err := validator.Validate(message.Data); err != nil {
+		err = fmt.Errorf("event %q failed validation: %w", event, err)
+		if o.validationMode == ValidateWarn {
+			o.logger.Println("sse validation warning: " + err.Error())
+			return nil
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,221 @@
+package sse
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// websocketGUID is appended to a client's key to compute the handshake's accept key
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// maxWebSocketFrame caps the payload of frames read from a websocket client
+const maxWebSocketFrame = 64 * 1024
+
+// WebSocket frame opcodes
+const (
+	opText  = 0x1
+	opClose = 0x8
+	opPing  = 0x9
+	opPong  = 0xA
+)
+
+// WithWebSocket serves websocket upgrade requests with the same Connection API, for clients
+// that need a websocket transport. Each event is sent as a text message of the json object
+// written by WithNDJSON. Messages from the client other than pings and closes are ignored.
+func WithWebSocket() Option {
+	return func(o *options) {
+		o.websocket = true
+	}
+}
+
+// wantsWebSocket returns whether request should be upgraded to a websocket
+func (o *options) wantsWebSocket(request *http.Request) bool {
+	return o.websocket && strings.EqualFold(request.Header.Get("Upgrade"), "websocket") &&
+		headerContainsToken(request.Header, "Connection", "upgrade")
+}
+
+// websocketResponse is the http.ResponseWriter of a connection upgraded to a websocket, writing
+// each json line written to it as a text message
+type websocketResponse struct {
+	conn     net.Conn
+	buffered *bufio.ReadWriter
+	header   http.Header
+	mutex    sync.Mutex
+}
+
+// acceptWebSocket completes the websocket handshake of request by hijacking its connection.
+// Browsers don't apply CORS to websockets, so handshakes from another origin are refused
+// with ErrOriginNotAllowed unless cors allows the origin.
+func acceptWebSocket(writer http.ResponseWriter, request *http.Request, cors *CORS) (*websocketResponse, error) {
+	key := request.Header.Get("Sec-WebSocket-Key")
+	if request.Header.Get("Sec-WebSocket-Version") != "13" || len(key) == 0 {
+		return nil, refuse(writer, http.StatusBadRequest, ErrUnsupportedWebSocket, 0)
+	}
+	if origin := request.Header.Get("Origin"); len(origin) > 0 && !sameOrigin(origin, request.Host) &&
+		(cors == nil || !cors.allowed(origin)) {
+		return nil, refuse(writer, http.StatusForbidden, ErrOriginNotAllowed, 0)
+	}
+	conn, buffered, err := http.NewResponseController(writer).Hijack()
+	if err != nil {
+		if errors.Is(err, http.ErrNotSupported) {
+			return nil, ErrStreamingUnsupported
+		}
+		return nil, err
+	}
+	accept := sha1.Sum([]byte(key + websocketGUID))
+	buffered.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(accept[:]) + "\r\n\r\n")
+	if err := buffered.Flush(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &websocketResponse{conn: conn, buffered: buffered, header: http.Header{}}, nil
+}
+
+func (response *websocketResponse) Header() http.Header {
+	return response.header
+}
+
+func (response *websocketResponse) WriteHeader(int) {}
+
+// Write sends each line of p as a text message
+func (response *websocketResponse) Write(p []byte) (int, error) {
+	response.mutex.Lock()
+	defer response.mutex.Unlock()
+	for _, line := range bytes.Split(p, []byte("\n")) {
+		if len(line) == 0 {
+			continue
+		}
+		if err := response.writeFrame(opText, line); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// FlushError flushes the messages written for http.ResponseController
+func (response *websocketResponse) FlushError() error {
+	response.mutex.Lock()
+	defer response.mutex.Unlock()
+	return response.buffered.Flush()
+}
+
+// SetWriteDeadline sets the deadline of writes for http.ResponseController
+func (response *websocketResponse) SetWriteDeadline(deadline time.Time) error {
+	return response.conn.SetWriteDeadline(deadline)
+}
+
+// writeFrame buffers an unmasked, unfragmented frame
+func (response *websocketResponse) writeFrame(opcode byte, payload []byte) error {
+	header := []byte{0x80 | opcode}
+	switch {
+	case len(payload) < 126:
+		header = append(header, byte(len(payload)))
+	case len(payload) <= 0xFFFF:
+		header = binary.BigEndian.AppendUint16(append(header, 126), uint16(len(payload)))
+	default:
+		header = binary.BigEndian.AppendUint64(append(header, 127), uint64(len(payload)))
+	}
+	if _, err := response.buffered.Write(header); err != nil {
+		return err
+	}
+	_, err := response.buffered.Write(payload)
+	return err
+}
+
+// control writes and flushes a control frame
+func (response *websocketResponse) control(opcode byte, payload []byte) error {
+	response.mutex.Lock()
+	defer response.mutex.Unlock()
+	if err := response.writeFrame(opcode, payload); err != nil {
+		return err
+	}
+	return response.buffered.Flush()
+}
+
+// read reads frames from the client, answering pings, until the client closes the websocket,
+// sends an unmasked frame or the connection fails, returning the cause
+func (response *websocketResponse) read() error {
+	for {
+		var header [2]byte
+		if _, err := io.ReadFull(response.buffered, header[:]); err != nil {
+			return err
+		}
+		opcode := header[0] & 0x0F
+		length := uint64(header[1] & 0x7F)
+		switch length {
+		case 126:
+			var extended [2]byte
+			if _, err := io.ReadFull(response.buffered, extended[:]); err != nil {
+				return err
+			}
+			length = uint64(binary.BigEndian.Uint16(extended[:]))
+		case 127:
+			var extended [8]byte
+			if _, err := io.ReadFull(response.buffered, extended[:]); err != nil {
+				return err
+			}
+			length = binary.BigEndian.Uint64(extended[:])
+		}
+		if length > maxWebSocketFrame {
+			return ErrWebSocketFrameTooLarge
+		}
+		if header[1]&0x80 == 0 {
+			return ErrUnmaskedFrame
+		}
+		var mask [4]byte
+		if _, err := io.ReadFull(response.buffered, mask[:]); err != nil {
+			return err
+		}
+		payload := make([]byte, length)
+		if _, err := io.ReadFull(response.buffered, payload); err != nil {
+			return err
+		}
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+		switch opcode {
+		case opClose:
+			return io.EOF
+		case opPing:
+			if err := response.control(opPong, payload); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// close sends a close frame and closes the hijacked connection
+func (response *websocketResponse) close() {
+	response.control(opClose, nil)
+	response.conn.Close()
+}
+
+// sameOrigin returns whether origin is served from host
+func sameOrigin(origin string, host string) bool {
+	parsed, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(parsed.Host, host)
+}
+
+// headerContainsToken returns whether the comma separated values of header include token
+func headerContainsToken(header http.Header, name string, token string) bool {
+	for _, value := range header.Values(name) {
+		for _, field := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(field), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
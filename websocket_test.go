@@ -0,0 +1,174 @@
+package sse
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// dialWebSocket sends a websocket handshake from origin to server, returning the connection
+// and the status of the handshake's response
+func dialWebSocket(t *testing.T, server *httptest.Server, origin string) (net.Conn, *bufio.Reader, int) {
+	conn, err := net.Dial("tcp", server.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	request, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	request.Header.Set("Upgrade", "websocket")
+	request.Header.Set("Connection", "Upgrade")
+	request.Header.Set("Sec-WebSocket-Version", "13")
+	request.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+	if len(origin) > 0 {
+		request.Header.Set("Origin", origin)
+	}
+	if err := request.Write(conn); err != nil {
+		t.Fatal(err)
+	}
+	reader := bufio.NewReader(conn)
+	response, err := http.ReadResponse(reader, request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return conn, reader, response.StatusCode
+}
+
+func TestWebSocketOrigin(t *testing.T) {
+	cors := WithCORS(CORS{AllowedOrigins: []string{"https://app.example.com"}})
+	tests := []struct {
+		name   string
+		cors   bool
+		origin string
+		want   int
+	}{
+		{"no origin", false, "", http.StatusSwitchingProtocols},
+		{"same origin", false, "", http.StatusSwitchingProtocols},
+		{"cross origin", false, "https://evil.example.com", http.StatusForbidden},
+		{"allowed by cors", true, "https://app.example.com", http.StatusSwitchingProtocols},
+		{"refused by cors", true, "https://evil.example.com", http.StatusForbidden},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			opts := []Option{WithWebSocket()}
+			if test.cors {
+				opts = append(opts, cors)
+			}
+			server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+				if connection, err := Upgrade(writer, request, opts...); err == nil {
+					connection.Close()
+				}
+			}))
+			defer server.Close()
+			origin := test.origin
+			if test.name == "same origin" {
+				origin = server.URL
+			}
+			conn, _, status := dialWebSocket(t, server, origin)
+			conn.Close()
+			if status != test.want {
+				t.Errorf("handshake from %q = %d, want %d", origin, status, test.want)
+			}
+		})
+	}
+}
+
+func TestWebSocketRejectsUnmaskedFrames(t *testing.T) {
+	closed := make(chan error, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		connection, err := Upgrade(writer, request, WithWebSocket())
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		connection.OnClose(func(reason error) { closed <- reason })
+		<-connection.Done()
+	}))
+	defer server.Close()
+	conn, _, status := dialWebSocket(t, server, "")
+	defer conn.Close()
+	if status != http.StatusSwitchingProtocols {
+		t.Fatalf("handshake = %d", status)
+	}
+	if _, err := conn.Write([]byte{0x80 | opText, 2, 'h', 'i'}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case reason := <-closed:
+		if reason != ErrUnmaskedFrame {
+			t.Errorf("closed with %v, want %v", reason, ErrUnmaskedFrame)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("connection not closed for unmasked frame")
+	}
+}
+
+func TestWebSocketErrors(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodGet, "/", nil)
+	request.Header.Set("Upgrade", "websocket")
+	request.Header.Set("Connection", "Upgrade")
+	request.Header.Set("Sec-WebSocket-Version", "8")
+	if _, err := Upgrade(recorder, request, WithWebSocket()); err != ErrUnsupportedWebSocket {
+		t.Errorf("Upgrade() = %v, want %v", err, ErrUnsupportedWebSocket)
+	}
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("responded %d", recorder.Code)
+	}
+
+	closed := make(chan error, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		connection, err := Upgrade(writer, request, WithWebSocket())
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		connection.OnClose(func(reason error) { closed <- reason })
+		<-connection.Done()
+	}))
+	defer server.Close()
+	conn, _, _ := dialWebSocket(t, server, "")
+	defer conn.Close()
+	conn.Write([]byte{0x80 | opText, 0x80 | 127, 0, 0, 0, 0, 0, 1, 0, 1})
+	select {
+	case reason := <-closed:
+		if reason != ErrWebSocketFrameTooLarge {
+			t.Errorf("closed with %v, want %v", reason, ErrWebSocketFrameTooLarge)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("connection not closed for a frame too large")
+	}
+}
+
+func TestWebSocketAcceptsMaskedFrames(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		connection, err := Upgrade(writer, request, WithWebSocket())
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		<-connection.Done()
+	}))
+	defer server.Close()
+	conn, reader, status := dialWebSocket(t, server, "")
+	defer conn.Close()
+	if status != http.StatusSwitchingProtocols {
+		t.Fatalf("handshake = %d", status)
+	}
+	mask := []byte{1, 2, 3, 4}
+	frame := append([]byte{0x80 | opPing, 0x80 | 2}, mask...)
+	frame = append(frame, 'h'^mask[0], 'i'^mask[1])
+	if _, err := conn.Write(frame); err != nil {
+		t.Fatal(err)
+	}
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	pong := make([]byte, 4)
+	if _, err := reader.Read(pong); err != nil {
+		t.Fatal(err)
+	}
+	if pong[0] != 0x80|opPong || !strings.HasSuffix(string(pong[:4]), "hi") {
+		t.Errorf("pong = %q", pong)
+	}
+}
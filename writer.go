@@ -0,0 +1,397 @@
+package sse
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+)
+
+// maxBatchSize caps how many bytes of queued events are assembled into a single write
+const maxBatchSize = 32 * 1024
+
+// streamWriter owns writing a connection's events to the http response from the writer
+// goroutine started by Upgrade
+type streamWriter struct {
+	connection      *Connection
+	writer          http.ResponseWriter
+	controller      *http.ResponseController
+	readerFrom      io.ReaderFrom
+	request         *http.Request
+	options         *options
+	clientKey       string
+	snapshot        *Message
+	generateID      func() string
+	ndjson          bool
+	longPoll        bool
+	lastEvent       time.Time
+	messages        <-chan Message
+	urgent          <-chan Message
+	shutdown        <-chan time.Duration
+	aborted         <-chan error
+	resumed         <-chan struct{}
+	retuned         <-chan struct{}
+	errors          chan<- error
+	batch           bytes.Buffer
+	batched         []Message
+	held            []Message
+	exhausted       string
+	failed          error
+	unflushed       int
+	flushRequested  bool
+	flushTimer      *time.Timer
+	flushDue        <-chan time.Time
+	delivered       []Message
+	limited         map[string]*limitedEvent
+	limitTimer      *time.Timer
+	limitDue        <-chan time.Time
+	heartbeatTicker *time.Ticker
+	heartbeatDue    <-chan time.Time
+}
+
+// run writes events until the connection terminates, then releases the connection's handler
+// and callbacks with the cause of termination
+func (w *streamWriter) run() {
+	reason := w.loop()
+	if w.unflushed > 0 {
+		w.handleError(w.flush())
+	}
+	if w.flushTimer != nil {
+		w.flushTimer.Stop()
+	}
+	if w.limitTimer != nil {
+		w.limitTimer.Stop()
+	}
+	if w.heartbeatTicker != nil {
+		w.heartbeatTicker.Stop()
+	}
+	w.connection.terminate(reason)
+	w.abandon()
+	close(w.errors)
+	w.options.audit(AuditClose, w.request, nil)
+}
+
+func (w *streamWriter) loop() error {
+	var expired <-chan time.Time
+	if !w.options.expiry.IsZero() {
+		expiryTimer := time.NewTimer(time.Until(w.options.expiry))
+		defer expiryTimer.Stop()
+		expired = expiryTimer.C
+	}
+	w.lastEvent = time.Now()
+	var idleTimer *time.Timer
+	var idle <-chan time.Time
+	if w.options.idleTimeout > 0 {
+		idleTimer = time.NewTimer(w.options.idleTimeout)
+		defer idleTimer.Stop()
+		idle = idleTimer.C
+	}
+	var lifetime <-chan time.Time
+	if w.options.maxLifetime > 0 {
+		lifetimeTimer := time.NewTimer(w.options.maxLifetime)
+		defer lifetimeTimer.Stop()
+		lifetime = lifetimeTimer.C
+	}
+	var pollTimeout <-chan time.Time
+	if w.longPoll {
+		pollTimer := time.NewTimer(w.options.longPollTimeout)
+		defer pollTimer.Stop()
+		pollTimeout = pollTimer.C
+	}
+	w.scheduleHeartbeat()
+
+	// the snapshot is delivered before receiving any messages so it always precedes live events.
+	// It doesn't complete a long poll, which would otherwise only ever return the snapshot to
+	// clients that have no event id to resume from.
+	if w.snapshot != nil && w.enqueue(w.snapshot) {
+		if err := w.deliver(); err != nil && err != ErrPollComplete {
+			return err
+		}
+	}
+
+	for {
+		if w.failed != nil {
+			return w.failed
+		}
+		// select picks between ready channels at random, so high priority messages are taken
+		// first without waiting
+		if message, ok := w.nextUrgent(); ok {
+			if err := w.receive(&message); err != nil {
+				return err
+			}
+			continue
+		}
+		select {
+		case message := <-w.urgent:
+			if err := w.receive(&message); err != nil {
+				return err
+			}
+		case message := <-w.messages:
+			// a high priority message sent alongside it is still written first
+			if urgent, ok := w.nextUrgent(); ok {
+				if w.enqueue(&urgent) {
+					w.drainUrgent()
+				}
+			}
+			if err := w.receive(&message); err != nil {
+				return err
+			}
+		case <-w.resumed:
+			if w.connection.IsPaused() {
+				break
+			}
+			pending := w.held
+			w.held = nil
+			for i := range pending {
+				if !w.enqueue(&pending[i]) {
+					break
+				}
+			}
+			if err := w.deliver(); err != nil {
+				return err
+			}
+		case <-w.limitDue:
+			w.releaseLimited(false)
+			if err := w.deliver(); err != nil {
+				return err
+			}
+		case <-w.flushDue:
+			w.handleError(w.flush())
+		case <-w.heartbeatDue:
+			w.writeHeartbeat()
+		case <-w.retuned:
+			w.scheduleHeartbeat()
+		case <-idle:
+			remaining := w.idle()
+			if remaining <= 0 {
+				return ErrIdleTimeout
+			}
+			idleTimer.Reset(remaining)
+		case <-expired:
+			w.closeWith(w.options.goAway(TokenExpiredEvent, ""))
+			return ErrConnectionExpired
+		case <-pollTimeout:
+			return ErrPollComplete
+		case <-lifetime:
+			return w.endLifetime()
+		case retry := <-w.shutdown:
+			return w.shutDown(retry)
+		case err := <-w.aborted:
+			if err == ErrAuthRevoked {
+				w.closeWith(w.options.goAway(AuthRevokedEvent, ""))
+			}
+			return err
+		case <-w.request.Context().Done():
+			return w.request.Context().Err()
+		case <-w.connection.ctx.Done():
+			return w.connection.ctx.Err()
+		}
+	}
+}
+
+// abandon reports the messages left unwritten by a terminated connection to their senders
+func (w *streamWriter) abandon() {
+	w.abandonLimited()
+	for i := range w.held {
+		w.held[i].written(ErrConnectionClosed)
+	}
+	w.held = nil
+	for {
+		message, ok := w.next()
+		if !ok {
+			return
+		}
+		message.release()
+		message.written(ErrConnectionClosed)
+	}
+}
+
+// receive delivers message along with the messages waiting behind it
+func (w *streamWriter) receive(message *Message) error {
+	if w.enqueue(message) {
+		w.drain()
+	}
+	return w.deliver()
+}
+
+// deliverQueued delivers every message waiting to be sent, including those held back by rate
+// limits
+func (w *streamWriter) deliverQueued() error {
+	for {
+		w.drain()
+		if err := w.deliver(); err != nil {
+			return err
+		}
+		if len(w.messages)+len(w.urgent) == 0 {
+			break
+		}
+	}
+	w.releaseLimited(true)
+	return w.deliver()
+}
+
+// drain enqueues messages that are already waiting to be sent, up to maxBatchSize
+func (w *streamWriter) drain() {
+	for w.batch.Len() < maxBatchSize {
+		message, ok := w.next()
+		if !ok || !w.enqueue(&message) {
+			return
+		}
+	}
+}
+
+// enqueue encodes a message onto the batch unless it would exceed the connection's budget.
+// Messages are held back instead while the connection is paused or by their event's rate
+// limit. Comments are always written and don't count against the budget.
+func (w *streamWriter) enqueue(message *Message) bool {
+	// batching stops at a flush so events sent after it aren't flushed with it
+	if message.isFlush {
+		w.flushRequested = true
+		return false
+	}
+	if message.isComment {
+		w.writeComment(message.Data)
+		return true
+	}
+	if message.isCoalesced {
+		if message = w.connection.takeCoalesced(message.coalesceKey); message == nil {
+			return true
+		}
+	}
+	if w.connection.IsPaused() {
+		w.held = hold(w.held, *message, w.options.pauseBufferSize)
+		return true
+	}
+	if w.limit(message) {
+		return true
+	}
+	return w.encode(message)
+}
+
+// encode encodes a message onto the batch, returning false if it would exceed the
+// connection's budget
+func (w *streamWriter) encode(message *Message) bool {
+	if len(message.ID) == 0 && w.generateID != nil {
+		message.ID = w.generateID()
+	}
+	start := w.batch.Len()
+	w.writeMessage(message)
+	if w.exhausted = w.connection.charge(int64(w.batch.Len() - start)); len(w.exhausted) > 0 {
+		w.batch.Truncate(start)
+		message.release()
+		message.written(ErrBudgetExhausted)
+		return false
+	}
+	// confirmed sends are flushed straight away so they can be confirmed
+	if message.onWritten != nil {
+		w.flushRequested = true
+	}
+	w.batched = append(w.batched, *message)
+	return true
+}
+
+// deliver writes the enqueued batch, returning an error if the connection has exhausted its
+// budget and was closed
+func (w *streamWriter) deliver() error {
+	delivered := len(w.batched) > 0
+	if delivered {
+		w.lastEvent = time.Now()
+	}
+	var err error
+	if w.batch.Len() > 0 {
+		err = w.write()
+	}
+	if w.flushRequested {
+		w.flushRequested = false
+		if flushErr := w.flush(); err == nil {
+			w.handleError(flushErr)
+			err = flushErr
+		}
+	}
+	for i := range w.batched {
+		w.batched[i].release()
+		w.batched[i].written(err)
+	}
+	w.batched = w.batched[:0]
+	if len(w.exhausted) > 0 {
+		w.closeWith(w.options.goAway(LimitReachedEvent, w.exhausted))
+		return fmt.Errorf("%w: %s", ErrBudgetExhausted, w.exhausted)
+	}
+	if delivered && w.longPoll {
+		return ErrPollComplete
+	}
+	return nil
+}
+
+// writeMessage encodes message onto the batch in the stream's format
+func (w *streamWriter) writeMessage(message *Message) {
+	if w.ndjson {
+		w.batch.Write(appendNDJSON(w.batch.AvailableBuffer(), message))
+	} else {
+		writeMessage(&w.batch, message)
+	}
+}
+
+// writeComment encodes text as comment lines onto the batch, which newline-delimited json
+// streams have no equivalent for
+func (w *streamWriter) writeComment(text []byte) {
+	if !w.ndjson {
+		writeComment(&w.batch, text)
+	}
+}
+
+// closeWith writes a final event before the server closes the connection
+func (w *streamWriter) closeWith(event string, data string) {
+	w.writeMessage(&Message{Event: event, Data: []byte(data)})
+	w.write()
+}
+
+// write hands the batch to the response and flushes it, unless flush coalescing defers the
+// flush. A write that misses its deadline fails the connection.
+func (w *streamWriter) write() error {
+	if timeout := w.connection.writeTimeout(); timeout > 0 {
+		w.controller.SetWriteDeadline(time.Now().Add(timeout))
+	}
+	var written int64
+	var err error
+	if w.readerFrom != nil {
+		written, err = w.readerFrom.ReadFrom(&w.batch)
+	} else {
+		var n int
+		n, err = w.writer.Write(w.batch.Bytes())
+		written = int64(n)
+	}
+	w.batch.Reset()
+	if err == nil {
+		w.unflushed += len(w.batched)
+		w.recordDelivered(w.batched)
+		if !w.deferFlush() {
+			err = w.flush()
+		}
+	}
+	if err == nil {
+		w.connection.recordWrite(written, len(w.batched))
+	}
+	w.handleError(err)
+	return err
+}
+
+func (w *streamWriter) handleError(err error) {
+	if err == nil {
+		return
+	}
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		w.failed = err
+	}
+	if w.options.reconnectMonitor != nil {
+		w.options.reconnectMonitor.recordError(w.clientKey)
+	}
+	select {
+	case w.errors <- err:
+	default:
+		w.options.logger.Println("sse write error: " + err.Error())
+	}
+}
@@ -0,0 +1,107 @@
+package sse
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func BenchmarkWriteMessage(b *testing.B) {
+	message := Message{ID: "1024", Event: "update", Data: []byte(`{"price":101.25,"symbol":"ACME"}`)}
+	var dst []byte
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		dst = appendMessage(dst[:0], &message)
+	}
+	b.SetBytes(int64(len(dst)))
+}
+
+func BenchmarkWriteMessageMultiline(b *testing.B) {
+	message := Message{Event: "html", Data: []byte(strings.Repeat("<li>item</li>\n", 32))}
+	var dst []byte
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		dst = appendMessage(dst[:0], &message)
+	}
+	b.SetBytes(int64(len(dst)))
+}
+
+// fprintfMessage encodes a message field by field with fmt.Fprintf, as the writer did before
+// appending events into the batch, as the baseline for the write benchmarks
+func fprintfMessage(batch *bytes.Buffer, message *Message) {
+	if len(message.ID) > 0 {
+		fmt.Fprintf(batch, "id: %s\n", message.ID)
+	}
+	if len(message.Event) > 0 {
+		fmt.Fprintf(batch, "event: %s\n", message.Event)
+	}
+	for _, line := range bytes.Split(message.Data, []byte("\n")) {
+		fmt.Fprintf(batch, "data: %s\n", line)
+	}
+	batch.WriteByte('\n')
+}
+
+func BenchmarkWriteMessageFprintf(b *testing.B) {
+	message := Message{ID: "1024", Event: "update", Data: []byte(`{"price":101.25,"symbol":"ACME"}`)}
+	var batch bytes.Buffer
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		batch.Reset()
+		fprintfMessage(&batch, &message)
+	}
+	b.SetBytes(int64(batch.Len()))
+}
+
+// benchmarkStream streams b.N events from a server started by start to its client, which an
+// HTTP/1.1 server's response receives in batches with ReadFrom and an HTTP/2 server's with Write
+func benchmarkStream(b *testing.B, protoMajor int, start func(server *httptest.Server)) {
+	message := Message{Event: "update", Data: []byte(`{"price":101.25,"symbol":"ACME"}`)}
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		connection, err := Upgrade(writer, request, WithQueueSize(256))
+		if err != nil {
+			b.Error(err)
+			return
+		}
+		for i := 0; i < b.N; i++ {
+			if err := connection.Send(message); err != nil {
+				b.Error(err)
+				return
+			}
+		}
+		connection.Close()
+	}))
+	start(server)
+	defer server.Close()
+	b.ReportAllocs()
+	b.ResetTimer()
+	response, err := server.Client().Get(server.URL)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer response.Body.Close()
+	if response.ProtoMajor != protoMajor {
+		b.Fatalf("served over %s", response.Proto)
+	}
+	written, err := io.Copy(io.Discard, response.Body)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.SetBytes(written / int64(b.N))
+}
+
+func BenchmarkStreamHTTP1(b *testing.B) {
+	benchmarkStream(b, 1, func(server *httptest.Server) {
+		server.Start()
+	})
+}
+
+func BenchmarkStreamHTTP2(b *testing.B) {
+	benchmarkStream(b, 2, func(server *httptest.Server) {
+		server.EnableHTTP2 = true
+		server.StartTLS()
+	})
+}